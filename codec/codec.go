@@ -9,6 +9,10 @@ const (
 	kindMap     = byte(6)
 	kindList    = byte(7)
 	kindHash    = byte(8)
+	kindTime    = byte(9)
+	kindBigInt  = byte(10)
+	kindDecimal = byte(11)
+	kindNull    = byte(12)
 
 	kindCommit     = byte(100)
 	kindDataRoot   = byte(101)
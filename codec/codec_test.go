@@ -2,8 +2,11 @@ package codec
 
 import (
 	"bytes"
+	"fmt"
 	"math"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/rodent-software/capy/object"
 	"github.com/stretchr/testify/assert"
@@ -25,12 +28,29 @@ var testInput = []any{
 	map[string]any{},
 	map[string]any{"count": int64(9)},
 	object.Sum([]byte("test")),
+	time.Date(2024, 2, 29, 12, 30, 0, 500, time.UTC),
+	big.NewInt(0),
+	new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 100)),
+	big.NewRat(-314, 100),
+	nil,
+	[]any{nil, "null"},
 	&object.Commit{
 		Parents:  []object.Hash{object.Sum([]byte("parent"))},
 		DataRoot: object.Sum([]byte("root")),
 	},
+	&object.Commit{
+		Parents:  []object.Hash{object.Sum([]byte("parent"))},
+		DataRoot: object.Sum([]byte("root")),
+		Time:     time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC),
+		Author:   "bob",
+		Message:  "add users",
+	},
+	&object.DataRoot{
+		Collections: map[string]object.Hash{"User": object.Sum([]byte("User"))},
+	},
 	&object.DataRoot{
 		Collections: map[string]object.Hash{"User": object.Sum([]byte("User"))},
+		Schema:      object.Sum([]byte("schema")),
 	},
 	&object.Collection{
 		Documents: map[string]object.Hash{"1": object.Sum([]byte("1"))},
@@ -58,3 +78,19 @@ func TestEncodeDecode(t *testing.T) {
 		assert.Equal(t, expect, actual)
 	}
 }
+
+func TestDecodeLargeCollection(t *testing.T) {
+	// values may span the boundary of the decoder read buffer
+	col := &object.Collection{Documents: make(map[string]object.Hash)}
+	for i := 0; i < 1000; i++ {
+		col.Documents[fmt.Sprintf("%032d", i)] = object.Sum([]byte{byte(i)})
+	}
+	var buffer bytes.Buffer
+	enc := NewEncoder(&buffer)
+	require.NoError(t, enc.Encode(col))
+	require.NoError(t, enc.Flush())
+
+	actual, err := NewDecoder(&buffer).DecodeCollection()
+	require.NoError(t, err)
+	assert.Equal(t, col, actual)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
+	"time"
 
 	"github.com/rodent-software/capy/object"
 )
@@ -51,6 +53,14 @@ func (e *Decoder) Decode() (any, error) {
 		return e.DecodeList()
 	case kindMap:
 		return e.DecodeMap()
+	case kindTime:
+		return e.DecodeTime()
+	case kindBigInt:
+		return e.DecodeBigInt()
+	case kindDecimal:
+		return e.DecodeDecimal()
+	case kindNull:
+		return nil, e.DecodeNull()
 	default:
 		return nil, fmt.Errorf("invalid codec kind %x", kind)
 	}
@@ -79,6 +89,16 @@ func (e *Decoder) DecodeCommit() (*object.Commit, error) {
 	for i, p := range parents {
 		commit.Parents[i] = p.(object.Hash)
 	}
+	if _, err := e.r.Peek(1); err == io.EOF {
+		return &commit, nil // commit does not contain metadata
+	}
+	metadata, err := e.DecodeMap()
+	if err != nil {
+		return nil, err
+	}
+	commit.Time, _ = metadata["time"].(time.Time)
+	commit.Author, _ = metadata["author"].(string)
+	commit.Message, _ = metadata["message"].(string)
 	return &commit, nil
 }
 
@@ -100,6 +120,13 @@ func (e *Decoder) DecodeDataRoot() (*object.DataRoot, error) {
 	for k, v := range collections {
 		dataRoot.Collections[k] = v.(object.Hash)
 	}
+	if _, err := e.r.Peek(1); err == io.EOF {
+		return &dataRoot, nil // data root does not contain a schema
+	}
+	dataRoot.Schema, err = e.DecodeHash()
+	if err != nil {
+		return nil, err
+	}
 	return &dataRoot, nil
 }
 
@@ -148,7 +175,7 @@ func (e *Decoder) DecodeHash() (object.Hash, error) {
 		return nil, err
 	}
 	value := make([]byte, size)
-	_, err = e.r.Read(value)
+	_, err = io.ReadFull(e.r, value)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +195,7 @@ func (e *Decoder) DecodeBytes() ([]byte, error) {
 		return nil, err
 	}
 	value := make([]byte, size)
-	_, err = e.r.Read(value)
+	_, err = io.ReadFull(e.r, value)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +215,7 @@ func (e *Decoder) DecodeString() (string, error) {
 		return "", err
 	}
 	value := make([]byte, size)
-	_, err = e.r.Read(value)
+	_, err = io.ReadFull(e.r, value)
 	if err != nil {
 		return "", err
 	}
@@ -290,6 +317,90 @@ func (e *Decoder) DecodeMap() (map[string]any, error) {
 	return value, nil
 }
 
+func (e *Decoder) DecodeTime() (time.Time, error) {
+	kind, err := e.r.ReadByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if kind != kindTime {
+		return time.Time{}, fmt.Errorf("unexpected codec kind %x", kind)
+	}
+	sec, err := e.readUint64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	nsec, err := e.readUint64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+}
+
+func (e *Decoder) DecodeBigInt() (*big.Int, error) {
+	kind, err := e.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if kind != kindBigInt {
+		return nil, fmt.Errorf("unexpected codec kind %x", kind)
+	}
+	return e.readBigInt()
+}
+
+func (e *Decoder) DecodeDecimal() (*big.Rat, error) {
+	kind, err := e.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if kind != kindDecimal {
+		return nil, fmt.Errorf("unexpected codec kind %x", kind)
+	}
+	num, err := e.readBigInt()
+	if err != nil {
+		return nil, err
+	}
+	denom, err := e.readBigInt()
+	if err != nil {
+		return nil, err
+	}
+	if denom.Sign() == 0 {
+		return nil, fmt.Errorf("invalid decimal denominator")
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+func (e *Decoder) DecodeNull() error {
+	kind, err := e.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if kind != kindNull {
+		return fmt.Errorf("unexpected codec kind %x", kind)
+	}
+	return nil
+}
+
+func (e *Decoder) readBigInt() (*big.Int, error) {
+	sign, err := e.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	size, err := e.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(e.r, data)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Int).SetBytes(data)
+	if sign == 0 {
+		value.Neg(value)
+	}
+	return value, nil
+}
+
 func (e *Decoder) readUint64() (uint64, error) {
 	result := uint64(0)
 	for i := 0; i < 8; i++ {
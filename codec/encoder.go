@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"slices"
+	"time"
 
 	"github.com/rodent-software/capy/object"
 )
@@ -48,6 +50,14 @@ func (e *Encoder) Encode(value any) error {
 		return e.EncodeList(t)
 	case map[string]any:
 		return e.EncodeMap(t)
+	case time.Time:
+		return e.EncodeTime(t)
+	case *big.Int:
+		return e.EncodeBigInt(t)
+	case *big.Rat:
+		return e.EncodeDecimal(t)
+	case nil:
+		return e.EncodeNull()
 	default:
 		return fmt.Errorf("no encoder for %T", value)
 	}
@@ -66,7 +76,24 @@ func (e *Encoder) EncodeCommit(value *object.Commit) error {
 	if err != nil {
 		return err
 	}
-	return e.EncodeHash(value.DataRoot)
+	err = e.EncodeHash(value.DataRoot)
+	if err != nil {
+		return err
+	}
+	metadata := make(map[string]any)
+	if !value.Time.IsZero() {
+		metadata["time"] = value.Time
+	}
+	if value.Author != "" {
+		metadata["author"] = value.Author
+	}
+	if value.Message != "" {
+		metadata["message"] = value.Message
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return e.EncodeMap(metadata)
 }
 
 func (e *Encoder) EncodeDataRoot(value *object.DataRoot) error {
@@ -78,7 +105,11 @@ func (e *Encoder) EncodeDataRoot(value *object.DataRoot) error {
 	for k, v := range value.Collections {
 		collections[k] = v
 	}
-	return e.EncodeMap(collections)
+	err = e.EncodeMap(collections)
+	if err != nil || value.Schema == nil {
+		return err
+	}
+	return e.EncodeHash(value.Schema)
 }
 
 func (e *Encoder) EncodeCollection(value *object.Collection) error {
@@ -214,6 +245,56 @@ func (e *Encoder) EncodeMap(value map[string]any) error {
 	return nil
 }
 
+func (e *Encoder) EncodeTime(value time.Time) error {
+	err := e.w.WriteByte(kindTime)
+	if err != nil {
+		return err
+	}
+	err = e.writeUint64(uint64(value.Unix()))
+	if err != nil {
+		return err
+	}
+	return e.writeUint64(uint64(value.Nanosecond()))
+}
+
+func (e *Encoder) EncodeBigInt(value *big.Int) error {
+	err := e.w.WriteByte(kindBigInt)
+	if err != nil {
+		return err
+	}
+	return e.writeBigInt(value)
+}
+
+func (e *Encoder) EncodeDecimal(value *big.Rat) error {
+	err := e.w.WriteByte(kindDecimal)
+	if err != nil {
+		return err
+	}
+	err = e.writeBigInt(value.Num())
+	if err != nil {
+		return err
+	}
+	return e.writeBigInt(value.Denom())
+}
+
+func (e *Encoder) EncodeNull() error {
+	return e.w.WriteByte(kindNull)
+}
+
+func (e *Encoder) writeBigInt(value *big.Int) error {
+	err := e.w.WriteByte(byte(value.Sign() + 1))
+	if err != nil {
+		return err
+	}
+	data := value.Bytes()
+	err = e.writeUint64(uint64(len(data)))
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
 func (e *Encoder) writeUint64(value uint64) error {
 	for i := 0; i < 8; i++ {
 		err := e.w.WriteByte(byte(value >> (i * 8)))
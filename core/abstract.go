@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// referenceSeparator separates the collection and id of a typed reference.
+//
+// Collection names are GraphQL names and cannot contain the separator.
+const referenceSeparator = ":"
+
+// FormatReference returns the typed reference to the document in the given collection with the matching id.
+//
+// Typed references are stored in interface and union fields that can reference documents in multiple collections.
+func FormatReference(collection, id string) string {
+	return collection + referenceSeparator + id
+}
+
+// ParseReference returns the collection and id of the typed reference value.
+func ParseReference(value any) (string, string, bool) {
+	ref, ok := value.(string)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(ref, referenceSeparator)
+}
+
+// possibleCollection returns true if the abstract type can reference documents in the collection.
+func (t *Transaction) possibleCollection(def *ast.Definition, collection string) bool {
+	return slices.ContainsFunc(schema_gen.PossibleCollections(t.schema, def), func(d *ast.Definition) bool {
+		return d.Name == collection
+	})
+}
+
+// createAbstract returns the typed reference to the document created or linked by the input.
+//
+// The input must contain exactly one field naming the collection of the document.
+func (t *Transaction) createAbstract(ctx context.Context, def *ast.Definition, value any) (string, error) {
+	input, ok := value.(map[string]any)
+	if !ok || len(input) != 1 {
+		return "", newValidationError(TypeErrorCode, "expected an object with exactly one field")
+	}
+	for collection, v := range input {
+		if !t.possibleCollection(def, collection) {
+			return "", newValidationError(TypeErrorCode, "%s is not a possible type of %s", collection, def.Name)
+		}
+		doc, ok := v.(map[string]any)
+		if !ok {
+			return "", &ValidationError{Path: ast.Path{ast.PathName(collection)}, Code: TypeErrorCode, Message: "expected an object"}
+		}
+		id, err := t.createRelation(ctx, &ast.Type{NamedType: collection}, doc)
+		if err != nil {
+			return "", prefixPath(err, ast.PathName(collection))
+		}
+		return FormatReference(collection, id), nil
+	}
+	return "", nil
+}
+
+// connectAbstract returns the typed reference after verifying the referenced document exists.
+func (t *Transaction) connectAbstract(ctx context.Context, def *ast.Definition, value any) (string, error) {
+	input, _ := value.(map[string]any)
+	collection, _ := input["collection"].(string)
+	if !t.possibleCollection(def, collection) {
+		return "", fmt.Errorf("%s is not a possible type of %s", collection, def.Name)
+	}
+	id, err := t.connectRelation(ctx, &ast.Type{NamedType: collection}, input["id"])
+	if err != nil {
+		return "", err
+	}
+	return FormatReference(collection, id), nil
+}
+
+// patchAbstract returns the typed reference after applying the relation patch operation.
+func (t *Transaction) patchAbstract(ctx context.Context, def *ast.Definition, value any, patch any) (any, error) {
+	p, ok := patch.(map[string]any)
+	if !ok || len(p) != 1 {
+		return nil, fmt.Errorf("patch must contain exactly one operation")
+	}
+	switch {
+	case p[connectPatch] != nil:
+		return t.connectAbstract(ctx, def, p[connectPatch])
+	case p[createPatch] != nil:
+		return t.createAbstract(ctx, def, p[createPatch])
+	case p[disconnectPatch] == true:
+		return nil, nil
+	case p[disconnectPatch] != nil:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("invalid relation patch")
+	}
+}
+
+// patchAbstractList returns the result of applying the relation patch operation to the list of typed references.
+func (t *Transaction) patchAbstractList(ctx context.Context, def *ast.Definition, value any, op string, arg any) (any, error) {
+	list, _ := value.([]any)
+	switch op {
+	case connectPatch:
+		result := slices.Clone(list)
+		for _, v := range patchList(arg) {
+			ref, err := t.connectAbstract(ctx, def, v)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(result, any(ref)) {
+				result = append(result, ref)
+			}
+		}
+		return result, nil
+	case disconnectPatch:
+		refs := make([]any, 0)
+		for _, v := range patchList(arg) {
+			input, _ := v.(map[string]any)
+			collection, _ := input["collection"].(string)
+			id, _ := input["id"].(string)
+			refs = append(refs, FormatReference(collection, id))
+		}
+		result := make([]any, 0, len(list))
+		for _, v := range list {
+			if !slices.Contains(refs, v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	case createPatch:
+		result := slices.Clone(list)
+		for i, v := range patchList(arg) {
+			ref, err := t.createAbstract(ctx, def, v)
+			if err != nil {
+				return nil, prefixPath(err, ast.PathIndex(i))
+			}
+			result = append(result, ref)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid patch operation %s", op)
+	}
+}
+
+// FilterReference returns true if the document in the given collection with the matching id matches the abstract type filter.
+func (t *Transaction) FilterReference(ctx context.Context, abstract, collection, id string, filter any) (bool, error) {
+	def, ok := t.schema.Types[abstract]
+	if !ok || !schema_gen.IsAbstract(def) {
+		return false, fmt.Errorf("abstract type does not exist: %s", abstract)
+	}
+	return t.filterAbstract(ctx, def, FormatReference(collection, id), filter)
+}
+
+// filterAbstract returns true if the document referenced by the typed reference matches the filter.
+func (t *Transaction) filterAbstract(ctx context.Context, def *ast.Definition, value any, filter any) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	collection, id, ok := ParseReference(value)
+	if !ok {
+		return false, nil
+	}
+	doc, err := t.ReadDocument(ctx, collection, id)
+	if err != nil {
+		return false, err
+	}
+	return t.filterAbstractDocument(ctx, def, collection, id, doc, filter)
+}
+
+// filterAbstractDocument returns true if the document matches the abstract type filter.
+//
+// Filters named after a collection only match documents in that collection.
+// All other fields are matched against the fields of the document.
+func (t *Transaction) filterAbstractDocument(ctx context.Context, def *ast.Definition, collection, id string, doc map[string]any, filter any) (bool, error) {
+	filters, ok := filter.(map[string]any)
+	if !ok {
+		return filter == nil, nil
+	}
+	target := t.schema.Types[collection]
+	for key, val := range filters {
+		var match bool
+		var err error
+		switch {
+		case key == andFilter:
+			match = true
+			for _, f := range patchList(val) {
+				match, err = t.filterAbstractDocument(ctx, def, collection, id, doc, f)
+				if err != nil || !match {
+					break
+				}
+			}
+		case key == orFilter:
+			list := patchList(val)
+			match = len(list) == 0
+			for _, f := range list {
+				match, err = t.filterAbstractDocument(ctx, def, collection, id, doc, f)
+				if err != nil || match {
+					break
+				}
+			}
+		case key == notFilter:
+			match, err = t.filterAbstractDocument(ctx, def, collection, id, doc, val)
+			match = !match
+		case t.possibleCollection(def, key):
+			if key == collection {
+				match, err = t.filterDocument(ctx, target, id, doc, val)
+			}
+		default:
+			match, err = t.filterDocument(ctx, target, id, doc, map[string]any{key: val})
+		}
+		if err != nil || !match {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// validateAbstract ensures the typed reference references an existing document in a possible collection.
+func (t *Transaction) validateAbstract(ctx context.Context, path ast.Path, def *ast.Definition, value any) ValidationErrors {
+	collection, id, ok := ParseReference(value)
+	if !ok || !t.possibleCollection(def, collection) {
+		return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: fmt.Sprintf("expected a reference to a %s document", def.Name)}}
+	}
+	exists, err := t.documentExists(ctx, collection, id)
+	if err != nil || !exists {
+		return ValidationErrors{{Path: path, Code: RelationErrorCode, Message: fmt.Sprintf("document not found %s", id)}}
+	}
+	return nil
+}
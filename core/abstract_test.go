@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbstractRelation(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	interface Attachment {
+		name: String
+	}
+	type Image implements Attachment {
+		name: String
+		width: Int
+	}
+	type Video implements Attachment {
+		name: String
+		length: Int
+	}
+	type Post {
+		attachments: [Attachment]
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	videoID, err := tx.CreateDocument(ctx, "Video", map[string]any{"name": "clip", "length": int64(10)})
+	require.NoError(t, err)
+
+	postID, err := tx.CreateDocument(ctx, "Post", map[string]any{
+		"attachments": []any{
+			map[string]any{"Image": map[string]any{"name": "photo", "width": int64(100)}},
+			map[string]any{"Video": map[string]any{"id": videoID}},
+		},
+	})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "Post", postID)
+	require.NoError(t, err)
+
+	attachments := doc["attachments"].([]any)
+	require.Len(t, attachments, 2)
+
+	collection, id, ok := ParseReference(attachments[1])
+	require.True(t, ok)
+	assert.Equal(t, "Video", collection)
+	assert.Equal(t, videoID, id)
+
+	match, err := tx.FilterDocument(ctx, "Post", postID, map[string]any{
+		"attachments": map[string]any{"any": []any{map[string]any{"Image": map[string]any{"width": map[string]any{"gt": int64(50)}}}}},
+	})
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = tx.FilterDocument(ctx, "Post", postID, map[string]any{
+		"attachments": map[string]any{"all": []any{map[string]any{"name": map[string]any{"eq": "clip"}}}},
+	})
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{
+		"attachments": []any{map[string]any{"Post": map[string]any{}}},
+	})
+	require.Error(t, err)
+
+	err = tx.DeleteDocument(ctx, "Video", videoID)
+	require.NoError(t, err)
+
+	doc, err = tx.ReadDocument(ctx, "Post", postID)
+	require.NoError(t, err)
+	assert.Len(t, doc["attachments"], 1)
+
+	err = tx.PatchDocument(ctx, "Post", postID, map[string]any{
+		"attachments": map[string]any{"disconnect": []any{map[string]any{"collection": "Image", "id": mustParseID(t, doc["attachments"].([]any)[0])}}},
+	})
+	require.NoError(t, err)
+
+	doc, err = tx.ReadDocument(ctx, "Post", postID)
+	require.NoError(t, err)
+	assert.Empty(t, doc["attachments"])
+}
+
+func mustParseID(t *testing.T, ref any) string {
+	_, id, ok := ParseReference(ref)
+	require.True(t, ok)
+	return id
+}
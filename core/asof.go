@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodent-software/capy/object"
+)
+
+// timeIndex maps times to the commits of the current branch.
+//
+// Commits are stored oldest first along the first parent chain of the indexed head.
+// Each entry also stores the earliest time of the commit and all newer commits so
+// that entries remain ordered even if clocks were skewed when they were created.
+type timeIndex struct {
+	mu      sync.Mutex
+	head    object.Hash
+	hashes  []object.Hash
+	created []time.Time
+	times   []time.Time
+	// positions maps commit hashes to their position in the index.
+	positions map[string]int
+}
+
+// EnableTimeIndex enables an in memory index used to find commits by time without walking the commit history.
+//
+// The index is built the first time it is used and is extended as the repo head advances.
+func (r *Repository) EnableTimeIndex() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.times == nil {
+		r.times = &timeIndex{positions: make(map[string]int)}
+	}
+}
+
+// CommitAsOf returns the hash of the newest commit on the current branch created at or before the given time.
+//
+// The first parents of the repo head are visited starting from the head and commits without a time are skipped.
+// Nil is returned if no commits were created at or before the time.
+func (r *Repository) CommitAsOf(ctx context.Context, at time.Time) (object.Hash, error) {
+	r.mu.RLock()
+	head, times := r.head, r.times
+	r.mu.RUnlock()
+	if times != nil {
+		times.mu.Lock()
+		defer times.mu.Unlock()
+		err := times.update(ctx, r, head)
+		if err != nil {
+			return nil, err
+		}
+		return times.find(at), nil
+	}
+	iter := r.CommitIterator(head).FirstParent()
+	for !iter.Done() {
+		hash, commit, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !commit.Time.IsZero() && !commit.Time.After(at) {
+			return hash, nil
+		}
+	}
+	return nil, nil
+}
+
+// update adds the commits between the head and the newest indexed commit on its first parent chain.
+//
+// Indexed commits that are not on the first parent chain of the head are removed.
+func (x *timeIndex) update(ctx context.Context, r *Repository, head object.Hash) error {
+	if head.Equal(x.head) {
+		return nil
+	}
+	var hashes []object.Hash
+	var created []time.Time
+	base := -1
+	iter := r.CommitIterator(head).FirstParent()
+	for !iter.Done() {
+		hash, commit, err := iter.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if pos, ok := x.positions[hash.String()]; ok {
+			base = pos
+			break
+		}
+		if commit.Time.IsZero() {
+			continue // commits created before metadata was recorded
+		}
+		hashes = append(hashes, hash)
+		created = append(created, commit.Time)
+	}
+	if base+1 < len(x.hashes) {
+		x.truncate(base + 1)
+	}
+	for i := len(hashes) - 1; i >= 0; i-- {
+		x.add(hashes[i], created[i])
+	}
+	x.head = head
+	return nil
+}
+
+// truncate removes all commits at or after the given position.
+func (x *timeIndex) truncate(pos int) {
+	for _, hash := range x.hashes[pos:] {
+		delete(x.positions, hash.String())
+	}
+	x.hashes = x.hashes[:pos]
+	x.created = x.created[:pos]
+	x.times = x.times[:pos]
+	// earliest times may have come from removed commits
+	for i := pos - 1; i >= 0; i-- {
+		x.times[i] = x.created[i]
+		if i+1 < pos && x.times[i+1].Before(x.times[i]) {
+			x.times[i] = x.times[i+1]
+		}
+	}
+}
+
+// add appends the commit to the index.
+func (x *timeIndex) add(hash object.Hash, created time.Time) {
+	x.positions[hash.String()] = len(x.hashes)
+	x.hashes = append(x.hashes, hash)
+	x.created = append(x.created, created)
+	x.times = append(x.times, created)
+	for i := len(x.times) - 2; i >= 0 && x.times[i].After(created); i-- {
+		x.times[i] = created
+	}
+}
+
+// find returns the newest indexed commit created at or before the given time.
+func (x *timeIndex) find(at time.Time) object.Hash {
+	i := sort.Search(len(x.times), func(i int) bool {
+		return x.times[i].After(at)
+	})
+	if i == 0 {
+		return nil
+	}
+	return x.hashes[i-1]
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rodent-software/capy/object"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitAt creates a commit on the repo head at the given time and returns its hash.
+func commitAt(t *testing.T, repo *Repository, now time.Time) object.Hash {
+	ctx := context.Background()
+	repo.clock = func() time.Time { return now }
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": now.String()})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+	return hash
+}
+
+func TestCommitAsOf(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		ctx := context.Background()
+		schema := `type User { name: String }`
+
+		repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+		require.NoError(t, err)
+		if indexed {
+			repo.EnableTimeIndex()
+		}
+
+		start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+		first := commitAt(t, repo, start)
+		second := commitAt(t, repo, start.Add(time.Hour))
+
+		hash, err := repo.CommitAsOf(ctx, start.Add(30*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, first, hash)
+
+		hash, err = repo.CommitAsOf(ctx, start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, second, hash)
+
+		hash, err = repo.CommitAsOf(ctx, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Nil(t, hash)
+
+		// commits created after the index was built are found
+		third := commitAt(t, repo, start.Add(2*time.Hour))
+		hash, err = repo.CommitAsOf(ctx, start.Add(3*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, third, hash)
+
+		// commits with skewed clocks are found before their older parents
+		skewed := commitAt(t, repo, start.Add(-time.Hour))
+		hash, err = repo.CommitAsOf(ctx, start.Add(-30*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, skewed, hash)
+
+		hash, err = repo.CommitAsOf(ctx, start.Add(3*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, skewed, hash)
+	}
+}
+
+func TestCommitAsOfResetHead(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+	repo.EnableTimeIndex()
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := commitAt(t, repo, start)
+	commitAt(t, repo, start.Add(time.Hour))
+
+	_, err = repo.CommitAsOf(ctx, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, repo.setHead(ctx, first))
+
+	// commits removed from the current branch are no longer found
+	hash, err := repo.CommitAsOf(ctx, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, first, hash)
+
+	other := commitAt(t, repo, start.Add(90*time.Minute))
+	hash, err = repo.CommitAsOf(ctx, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, other, hash)
+}
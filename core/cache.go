@@ -0,0 +1,205 @@
+package core
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/rodent-software/capy/codec"
+	"github.com/rodent-software/capy/object"
+)
+
+// DefaultCacheSize is the default byte budget of the decoded object cache.
+const DefaultCacheSize = 64 << 20
+
+// CacheStats contains statistics about the decoded object cache of a repo.
+type CacheStats struct {
+	// Hits is the number of objects returned from the cache.
+	Hits uint64
+	// Misses is the number of objects that were loaded and decoded.
+	Misses uint64
+	// Evictions is the number of objects removed to stay within the byte budget.
+	Evictions uint64
+	// Entries is the number of cached objects.
+	Entries int
+	// Size is the encoded size in bytes of all cached objects.
+	Size int64
+	// Capacity is the byte budget of the cache.
+	Capacity int64
+}
+
+// objectCache is a least recently used cache of decoded objects keyed by hash.
+//
+// Objects are immutable by hash so cached values never need to be invalidated.
+// The size of each object is measured by its encoded length.
+type objectCache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	entries  map[string]*list.Element
+	// order contains the cached objects with the most recently used at the front.
+	order *list.List
+	stats CacheStats
+}
+
+// cacheEntry is a decoded object stored in the cache.
+type cacheEntry struct {
+	key   string
+	value any
+	size  int64
+}
+
+// newObjectCache returns a cache that holds objects up to the given encoded size in bytes.
+func newObjectCache(capacity int64) *objectCache {
+	return &objectCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached object with the given key.
+func (c *objectCache) get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).value, true
+}
+
+// add stores the object in the cache and evicts the least recently used objects that exceed the capacity.
+func (c *objectCache) add(key string, value any, size int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok || size > c.capacity {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, size: size})
+	c.size += size
+	c.evict()
+}
+
+// remove deletes the object with the given key from the cache.
+func (c *objectCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, key)
+	c.size -= elem.Value.(*cacheEntry).size
+}
+
+// resize changes the capacity of the cache and evicts objects that no longer fit.
+func (c *objectCache) resize(capacity int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+// evict removes the least recently used objects until the cache is within its capacity.
+func (c *objectCache) evict() {
+	for c.size > c.capacity {
+		elem := c.order.Back()
+		entry := c.order.Remove(elem).(*cacheEntry)
+		delete(c.entries, entry.key)
+		c.size -= entry.size
+		c.stats.Evictions++
+	}
+}
+
+// CacheStats returns statistics about the decoded object cache.
+func (r *Repository) CacheStats() CacheStats {
+	if r.cache == nil {
+		return CacheStats{}
+	}
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+	stats := r.cache.stats
+	stats.Entries = len(r.cache.entries)
+	stats.Size = r.cache.size
+	stats.Capacity = r.cache.capacity
+	return stats
+}
+
+// SetCacheSize sets the byte budget of the decoded object cache.
+//
+// A size of zero disables the cache.
+func (r *Repository) SetCacheSize(size int64) {
+	r.cache.resize(size)
+}
+
+func (r *Repository) objectCache() *objectCache {
+	return r.cache
+}
+
+func (t *Transaction) objectCache() *objectCache {
+	return t.repo.cache
+}
+
+// decodeObject returns the object with the given hash loaded and decoded using the given functions.
+//
+// Decoded objects are shared through the cache and must not be modified.
+func decodeObject[T any](ctx context.Context, cache *objectCache, hash object.Hash, load func(context.Context, object.Hash) ([]byte, error), decode func(*codec.Decoder) (T, error)) (T, error) {
+	if value, ok := cache.get(hash.String()); ok {
+		if v, ok := value.(T); ok {
+			return v, nil
+		}
+	}
+	var value T
+	data, err := load(ctx, hash)
+	if err != nil {
+		return value, err
+	}
+	value, err = decode(codec.NewDecoder(bytes.NewBuffer(data)))
+	if err != nil {
+		return value, err
+	}
+	cache.add(hash.String(), value, int64(len(data)))
+	return value, nil
+}
+
+// copyValue returns a deep copy of the decoded value.
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case object.Document:
+		return object.Document(copyValue(map[string]any(v)).(map[string]any))
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = copyValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = copyValue(val)
+		}
+		return out
+	case []byte:
+		return bytes.Clone(v)
+	default:
+		return value
+	}
+}
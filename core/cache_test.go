@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectCacheEviction(t *testing.T) {
+	cache := newObjectCache(10)
+	cache.add("a", 1, 4)
+	cache.add("b", 2, 4)
+
+	// reading a makes b the least recently used object
+	value, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.add("c", 3, 4)
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+
+	// objects larger than the capacity are not cached
+	cache.add("d", 4, 11)
+	_, ok = cache.get("d")
+	assert.False(t, ok)
+
+	cache.resize(0)
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, uint64(2), cache.stats.Hits)
+	assert.Equal(t, uint64(3), cache.stats.Misses)
+	assert.Equal(t, uint64(3), cache.stats.Evictions)
+	assert.Equal(t, int64(0), cache.size)
+}
+
+func TestRepositoryCacheStats(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	read, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	_, err = read.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	before := repo.CacheStats()
+
+	for i := 0; i < 3; i++ {
+		doc, err := read.ReadDocument(ctx, "User", id)
+		require.NoError(t, err)
+		// documents are copied so callers cannot modify cached values
+		doc["name"] = "Alice"
+	}
+	stats := repo.CacheStats()
+	assert.Equal(t, before.Hits+6, stats.Hits)
+	assert.Equal(t, before.Misses, stats.Misses)
+	assert.Equal(t, int64(DefaultCacheSize), stats.Capacity)
+	assert.Greater(t, stats.Entries, 0)
+
+	doc, err := read.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", doc["name"])
+
+	repo.SetCacheSize(0)
+	stats = repo.CacheStats()
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, int64(0), stats.Size)
+}
+
+func TestCachedCollectionsAreNotModified(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	// both transactions load the same cached collection
+	_, err = txB.DocumentIterator(ctx, "User")
+	require.NoError(t, err)
+	id, err := txA.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	_, err = txA.Commit(ctx)
+	require.NoError(t, err)
+
+	exists, err := txB.documentExists(ctx, "User", id)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSharedIndexes(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { email: String @unique } type Post { author: User }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "email": "bob@example.com"})
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"id": "1", "author": map[string]any{"id": "bob"}})
+	require.NoError(t, err)
+	_, err = tx.references(ctx, "User", "bob")
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	// transactions based on the commit use the indexes of the committed data root
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	refs, err := txA.referenceIndex(ctx)
+	require.NoError(t, err)
+	assert.Same(t, tx.refs, refs)
+	uniques, err := txA.uniqueIndex(ctx)
+	require.NoError(t, err)
+	assert.Same(t, tx.uniques, uniques)
+
+	// shared indexes are copied before they are modified
+	err = txA.DeleteDocument(ctx, "Post", "1")
+	require.NoError(t, err)
+	err = txA.PatchDocument(ctx, "User", "bob", map[string]any{"email": map[string]any{"set": "alice@example.com"}})
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	posts, err := txB.references(ctx, "User", "bob")
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+	_, err = txB.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, UniqueErrorCode, errs[0].Code)
+}
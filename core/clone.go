@@ -0,0 +1,276 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rodent-software/capy/codec"
+	"github.com/rodent-software/capy/object"
+)
+
+// Fetcher retrieves the encoded objects that are missing from a partial repo.
+type Fetcher interface {
+	// Fetch returns the encoded object with the given hash.
+	Fetch(ctx context.Context, hash object.Hash) ([]byte, error)
+}
+
+// storageFetcher is a Fetcher that reads objects from another storage backend.
+type storageFetcher struct {
+	storage Storage
+}
+
+// NewStorageFetcher returns a Fetcher that reads objects from the given storage.
+func NewStorageFetcher(storage Storage) Fetcher {
+	return &storageFetcher{storage: storage}
+}
+
+func (f *storageFetcher) Fetch(ctx context.Context, hash object.Hash) ([]byte, error) {
+	return f.storage.Get(ctx, hash.String())
+}
+
+// CloneOptions describes which objects are copied when a repo is cloned.
+type CloneOptions struct {
+	// Depth is the number of commits copied starting from the source head.
+	// All commits are copied if it is zero.
+	Depth int
+	// Collections contains the names of the collections whose documents are copied.
+	// All collections are copied if it is empty.
+	Collections []string
+}
+
+// CloneRepository copies the repo from the source storage into the given storage.
+//
+// Commits beyond the depth are not copied and the oldest copied commits are recorded
+// as shallow commits. Collections that are not copied are retrieved from the source
+// storage when they are first read.
+func CloneRepository(ctx context.Context, source, storage Storage, options CloneOptions) (*Repository, error) {
+	head, err := source.Get(ctx, HeadKey)
+	if err != nil {
+		return nil, err
+	}
+	c := &cloner{
+		source:  source,
+		storage: storage,
+		options: options,
+		copied:  make(map[string]struct{}),
+	}
+	shallow, err := c.copyCommits(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	// repos created before schemas were versioned store the schema separately
+	schema, err := source.Get(ctx, SchemaKey)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		err = storage.Put(ctx, SchemaKey, schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(shallow) > 0 {
+		err = storage.Put(ctx, ShallowKey, encodeShallow(shallow))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = storage.Put(ctx, HeadKey, head)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := OpenRepository(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	if len(options.Collections) > 0 {
+		repo.SetFetcher(NewStorageFetcher(source))
+	}
+	return repo, nil
+}
+
+// cloner copies objects from a source storage.
+type cloner struct {
+	source  Storage
+	storage Storage
+	options CloneOptions
+	// copied contains the keys of objects that have been copied.
+	copied map[string]struct{}
+}
+
+// copyCommits copies the commits reachable from the head up to the clone depth and returns the shallow commits.
+func (c *cloner) copyCommits(ctx context.Context, head object.Hash) ([]object.Hash, error) {
+	var shallow []object.Hash
+	next := []object.Hash{head}
+	for depth := 1; len(next) > 0; depth++ {
+		var parents []object.Hash
+		for _, hash := range next {
+			data, err := c.copyObject(ctx, hash)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				continue // reached through multiple parents
+			}
+			commit, err := codec.NewDecoder(bytes.NewBuffer(data)).DecodeCommit()
+			if err != nil {
+				return nil, err
+			}
+			err = c.copyDataRoot(ctx, commit.DataRoot)
+			if err != nil {
+				return nil, err
+			}
+			if c.options.Depth > 0 && depth >= c.options.Depth && len(commit.Parents) > 0 {
+				shallow = append(shallow, hash)
+				continue
+			}
+			for _, p := range commit.Parents {
+				if _, ok := c.copied[p.String()]; !ok {
+					parents = append(parents, p)
+				}
+			}
+		}
+		next = parents
+	}
+	return shallow, nil
+}
+
+// copyDataRoot copies the data root and the collections selected by the clone options.
+func (c *cloner) copyDataRoot(ctx context.Context, hash object.Hash) error {
+	data, err := c.copyObject(ctx, hash)
+	if err != nil || data == nil {
+		return err
+	}
+	dataRoot, err := codec.NewDecoder(bytes.NewBuffer(data)).DecodeDataRoot()
+	if err != nil {
+		return err
+	}
+	if dataRoot.Schema != nil {
+		_, err = c.copyObject(ctx, dataRoot.Schema)
+		if err != nil {
+			return err
+		}
+	}
+	for name, colHash := range dataRoot.Collections {
+		if !c.selected(name) {
+			continue
+		}
+		data, err := c.copyObject(ctx, colHash)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue // already copied
+		}
+		col, err := codec.NewDecoder(bytes.NewBuffer(data)).DecodeCollection()
+		if err != nil {
+			return err
+		}
+		for _, docHash := range col.Documents {
+			_, err = c.copyObject(ctx, docHash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selected returns true if the documents of the collection are copied.
+func (c *cloner) selected(collection string) bool {
+	if len(c.options.Collections) == 0 {
+		return true
+	}
+	for _, name := range c.options.Collections {
+		if name == collection {
+			return true
+		}
+	}
+	return false
+}
+
+// copyObject copies the encoded object and returns it or nil if it has already been copied.
+func (c *cloner) copyObject(ctx context.Context, hash object.Hash) ([]byte, error) {
+	if _, ok := c.copied[hash.String()]; ok {
+		return nil, nil
+	}
+	data, err := c.source.Get(ctx, hash.String())
+	if err != nil {
+		return nil, err
+	}
+	err = c.storage.Put(ctx, hash.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	c.copied[hash.String()] = struct{}{}
+	return data, nil
+}
+
+// SetFetcher sets the fetcher used to retrieve collections and documents missing from the repo.
+func (r *Repository) SetFetcher(fetcher Fetcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetcher = fetcher
+}
+
+// IsShallow returns true if the parents of the commit with the given hash are not stored in the repo.
+func (r *Repository) IsShallow(hash object.Hash) bool {
+	_, ok := r.shallow[hash.String()]
+	return ok
+}
+
+// loadObject returns the encoded object with the given hash.
+//
+// Objects missing from storage are retrieved using the fetcher and stored locally.
+func (r *Repository) loadObject(ctx context.Context, hash object.Hash) ([]byte, error) {
+	data, err := r.storage.Get(ctx, hash.String())
+	r.mu.RLock()
+	fetcher := r.fetcher
+	r.mu.RUnlock()
+	if !errors.Is(err, ErrNotFound) || fetcher == nil {
+		return data, err
+	}
+	data, err = fetcher.Fetch(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !object.Sum(data).Equal(hash) {
+		return nil, fmt.Errorf("fetched object does not match hash %s", hash)
+	}
+	err = r.storage.Put(ctx, hash.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// loadShallow returns the shallow commits stored in the given storage.
+func loadShallow(ctx context.Context, storage Storage) (map[string]struct{}, error) {
+	data, err := storage.Get(ctx, ShallowKey)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	size := len(object.Sum(nil))
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("invalid shallow commits")
+	}
+	shallow := make(map[string]struct{}, len(data)/size)
+	for i := 0; i < len(data); i += size {
+		shallow[object.Hash(data[i:i+size]).String()] = struct{}{}
+	}
+	return shallow, nil
+}
+
+// encodeShallow returns the shallow commits encoded as concatenated hashes.
+func encodeShallow(hashes []object.Hash) []byte {
+	var data []byte
+	for _, hash := range hashes {
+		data = append(data, hash...)
+	}
+	return data
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneShallow(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	source := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, source, schema)
+	require.NoError(t, err)
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitAt(t, repo, start)
+	second := commitAt(t, repo, start.Add(time.Hour))
+	third := commitAt(t, repo, start.Add(2*time.Hour))
+
+	storage := NewMemoryStorage()
+	clone, err := CloneRepository(ctx, source, storage, CloneOptions{Depth: 2})
+	require.NoError(t, err)
+	assert.Equal(t, third, clone.Head())
+	assert.True(t, clone.IsShallow(second))
+
+	commits := make([]string, 0)
+	iter := clone.CommitIterator(clone.Head())
+	for !iter.Done() {
+		hash, _, err := iter.Next(ctx)
+		require.NoError(t, err)
+		commits = append(commits, hash.String())
+	}
+	assert.Equal(t, []string{third.String(), second.String()}, commits)
+
+	// commits at the depth boundary have no parents
+	opened, err := OpenRepository(ctx, storage)
+	require.NoError(t, err)
+	commit, err := opened.Commit(ctx, second)
+	require.NoError(t, err)
+	assert.Empty(t, commit.Parents)
+
+	tx, err := opened.Transaction(ctx, opened.Head())
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, opened.Merge(ctx, hash))
+
+	base, err := opened.MergeBase(ctx, hash, second)
+	require.NoError(t, err)
+	assert.Equal(t, second, base)
+}
+
+func TestClonePartial(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User { name: String }
+	type Post { title: String }`
+	source := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, source, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	post, err := tx.CreateDocument(ctx, "Post", map[string]any{"title": "Hello"})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	storage := NewMemoryStorage()
+	clone, err := CloneRepository(ctx, source, storage, CloneOptions{Collections: []string{"User"}})
+	require.NoError(t, err)
+
+	tx, err = clone.Transaction(ctx, clone.Head())
+	require.NoError(t, err)
+	colHash := tx.data.Collections["Post"]
+	_, err = storage.Get(ctx, colHash.String())
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// missing objects are fetched and stored locally
+	doc, err := tx.ReadDocument(ctx, "Post", post)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", doc["title"])
+	_, err = storage.Get(ctx, colHash.String())
+	require.NoError(t, err)
+
+	// objects are not fetched without a fetcher
+	partial, err := CloneRepository(ctx, source, NewMemoryStorage(), CloneOptions{Collections: []string{"User"}})
+	require.NoError(t, err)
+	partial.SetFetcher(nil)
+	tx, err = partial.Transaction(ctx, partial.Head())
+	require.NoError(t, err)
+	_, err = tx.ReadDocument(ctx, "Post", post)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rodent-software/capy/object"
+)
+
+// CompactionPolicy describes which commits are kept when history is compacted.
+//
+// Commits older than KeepRecent are replaced with one snapshot per Interval
+// containing the data of the newest commit created during that interval.
+type CompactionPolicy struct {
+	// KeepRecent is the age of commits that are always kept.
+	KeepRecent time.Duration
+	// Interval is the period of time covered by each snapshot of older commits.
+	Interval time.Duration
+}
+
+// historyEntry is a commit on the first parent chain of the repo head.
+type historyEntry struct {
+	hash   object.Hash
+	commit *object.Commit
+}
+
+// Squash replaces the commits after from up to and including to with a single commit.
+//
+// The squashed commit contains the data root of to. Commits created after to
+// are rewritten on top of the squashed commit so the head data root is preserved.
+// Both commits must be on the first parent chain of the repo head.
+func (r *Repository) Squash(ctx context.Context, from, to object.Hash, message string) error {
+	r.update.Lock()
+	defer r.update.Unlock()
+	chain, err := r.firstParentChain(ctx, from)
+	if err != nil {
+		return err
+	}
+	end := -1
+	for i, entry := range chain {
+		if entry.hash.Equal(to) {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return fmt.Errorf("commit %s is not on the current branch after %s", to, from)
+	}
+	groups := [][]historyEntry{chain[:end+1]}
+	for _, entry := range chain[end+1:] {
+		groups = append(groups, []historyEntry{entry})
+	}
+	head, err := r.rewriteHistory(ctx, from, groups, message)
+	if err != nil {
+		return err
+	}
+	return r.setHead(ctx, head)
+}
+
+// Compact rewrites the history of the current branch according to the policy.
+//
+// Merged branches of replaced commits are dropped from history and can be removed by GC.
+func (r *Repository) Compact(ctx context.Context, policy CompactionPolicy) error {
+	if policy.Interval <= 0 {
+		return fmt.Errorf("compaction interval must be positive")
+	}
+	r.update.Lock()
+	defer r.update.Unlock()
+	chain, err := r.firstParentChain(ctx, nil)
+	if err != nil {
+		return err
+	}
+	cutoff := r.clock().Add(-policy.KeepRecent)
+	var groups [][]historyEntry
+	for _, entry := range chain {
+		last := len(groups) - 1
+		if !entry.commit.Time.Before(cutoff) {
+			groups = append(groups, []historyEntry{entry})
+			continue
+		}
+		bucket := entry.commit.Time.Truncate(policy.Interval)
+		if last >= 0 && groups[last][0].commit.Time.Before(cutoff) && groups[last][0].commit.Time.Truncate(policy.Interval).Equal(bucket) {
+			groups[last] = append(groups[last], entry)
+			continue
+		}
+		groups = append(groups, []historyEntry{entry})
+	}
+	head, err := r.rewriteHistory(ctx, nil, groups, "")
+	if err != nil {
+		return err
+	}
+	return r.setHead(ctx, head)
+}
+
+// firstParentChain returns the first parent chain of the repo head ordered oldest first.
+//
+// The chain stops before the given ancestor or includes the root commit if it is nil.
+func (r *Repository) firstParentChain(ctx context.Context, ancestor object.Hash) ([]historyEntry, error) {
+	var chain []historyEntry
+	iter := r.CommitIterator(r.Head()).FirstParent()
+	for {
+		if iter.Done() {
+			if ancestor != nil {
+				return nil, fmt.Errorf("commit %s is not on the current branch", ancestor)
+			}
+			break
+		}
+		hash, commit, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if hash.Equal(ancestor) {
+			break
+		}
+		chain = append(chain, historyEntry{hash, commit})
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// rewriteHistory creates a commit for each group of commits starting from the given parent and returns the new head.
+//
+// Groups containing a single commit keep their metadata and merge parents. Larger groups are
+// squashed into a commit containing the data root and metadata of their newest commit, and
+// the message replaces the squashed commit message if it is not empty.
+func (r *Repository) rewriteHistory(ctx context.Context, parent object.Hash, groups [][]historyEntry, message string) (object.Hash, error) {
+	for _, group := range groups {
+		newest := group[len(group)-1]
+		var parents []object.Hash
+		if parent != nil {
+			parents = append(parents, parent)
+		}
+		if len(group) == 1 && len(newest.commit.Parents) > 1 {
+			parents = append(parents, newest.commit.Parents[1:]...)
+		}
+		commit := &object.Commit{
+			Parents:  parents,
+			DataRoot: newest.commit.DataRoot,
+			Time:     newest.commit.Time,
+			Author:   newest.commit.Author,
+			Message:  newest.commit.Message,
+		}
+		if len(group) > 1 && message != "" {
+			commit.Message = message
+		}
+		hash, err := EncodeObject(ctx, r.storage, commit)
+		if err != nil {
+			return nil, err
+		}
+		parent = hash
+	}
+	return parent, nil
+}
+
+// GC removes all objects that are not reachable from the repo head and returns the number of removed objects.
+//
+// Objects reachable from the commits open transactions are based on are kept.
+// Commits that have not been merged into the head are removed. The storage
+// must implement CollectableStorage.
+func (r *Repository) GC(ctx context.Context) (int, error) {
+	storage, ok := r.storage.(CollectableStorage)
+	if !ok {
+		return 0, fmt.Errorf("storage does not support garbage collection")
+	}
+	r.update.Lock()
+	defer r.update.Unlock()
+	reachable, err := r.reachableObjects(ctx)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := storage.Keys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var removed int
+	for _, key := range keys {
+		if _, ok := reachable[key]; ok || !isObjectKey(key) {
+			continue
+		}
+		err = storage.Delete(ctx, key)
+		if err != nil {
+			return removed, err
+		}
+		r.cache.remove(key)
+		removed++
+	}
+	return removed, nil
+}
+
+// reachableObjects returns the set of object keys reachable from the repo head and the roots of open transactions.
+func (r *Repository) reachableObjects(ctx context.Context) (map[string]struct{}, error) {
+	reachable := make(map[string]struct{})
+	mark := func(hash object.Hash) bool {
+		_, ok := reachable[hash.String()]
+		reachable[hash.String()] = struct{}{}
+		return !ok
+	}
+	for _, root := range r.liveRoots() {
+		err := r.markCommits(ctx, root, mark)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reachable, nil
+}
+
+// markCommits marks the objects reachable from the commit with the given hash.
+//
+// The history of commits that were already marked is skipped.
+func (r *Repository) markCommits(ctx context.Context, hash object.Hash, mark func(object.Hash) bool) error {
+	iter := r.CommitIterator(hash)
+	for !iter.Done() {
+		hash, commit, err := iter.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if !mark(hash) {
+			iter.Skip()
+			continue
+		}
+		if !mark(commit.DataRoot) {
+			continue
+		}
+		dataRoot, err := r.DataRoot(ctx, commit.DataRoot)
+		if err != nil {
+			return err
+		}
+		if dataRoot.Schema != nil {
+			mark(dataRoot.Schema)
+		}
+		for _, colHash := range dataRoot.Collections {
+			if !mark(colHash) {
+				continue
+			}
+			_, err := r.storage.Get(ctx, colHash.String())
+			if errors.Is(err, ErrNotFound) {
+				continue // not materialized in partial repos
+			}
+			col, err := r.Collection(ctx, colHash)
+			if err != nil {
+				return err
+			}
+			for _, docHash := range col.Documents {
+				mark(docHash)
+			}
+		}
+	}
+	return nil
+}
+
+// isObjectKey returns true if the storage key is the hash of an object.
+func isObjectKey(key string) bool {
+	b, err := hex.DecodeString(key)
+	return err == nil && len(b) == len(object.Sum(nil))
+}
+
+// retainRoot keeps the objects reachable from the commit with the given hash during GC.
+func (r *Repository) retainRoot(hash object.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.roots == nil {
+		r.roots = make(map[string]int)
+	}
+	r.roots[hash.String()]++
+}
+
+// releaseRoot allows GC to remove the objects reachable from the commit with the given hash once it is no longer retained.
+func (r *Repository) releaseRoot(hash object.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roots[hash.String()]--
+	if r.roots[hash.String()] <= 0 {
+		delete(r.roots, hash.String())
+	}
+}
+
+// liveRoots returns the repo head and the commits retained by open transactions.
+func (r *Repository) liveRoots() []object.Hash {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	roots := []object.Hash{r.head}
+	for key := range r.roots {
+		hash, err := hex.DecodeString(key)
+		if err == nil {
+			roots = append(roots, object.Hash(hash))
+		}
+	}
+	return roots
+}
+
+// releaseRoots releases the commits retained by the transaction.
+func (t *Transaction) releaseRoots() {
+	for _, hash := range t.roots {
+		t.repo.releaseRoot(hash)
+	}
+	t.roots = nil
+}
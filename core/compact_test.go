@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquash(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+	root := repo.Head()
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitAt(t, repo, start)
+	second := commitAt(t, repo, start.Add(time.Hour))
+	commitAt(t, repo, start.Add(2*time.Hour))
+
+	head, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = repo.Squash(ctx, root, second, "Add users")
+	require.NoError(t, err)
+
+	commits := make([]string, 0)
+	iter := repo.CommitIterator(repo.Head())
+	for !iter.Done() {
+		_, commit, err := iter.Next(ctx)
+		require.NoError(t, err)
+		commits = append(commits, commit.Message)
+	}
+	assert.Equal(t, []string{"", "Add users", ""}, commits)
+
+	squashed, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+	assert.Equal(t, head.DataRoot, squashed.DataRoot)
+
+	removed, err := repo.GC(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, removed) // rewritten commits and the intermediate data root and collection
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	iterDocs, err := tx.DocumentIterator(ctx, "User")
+	require.NoError(t, err)
+	var count int
+	for !iterDocs.Done() {
+		_, _, _, err := iterDocs.Next(ctx)
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 3, count)
+
+	removed, err = repo.GC(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestSquashInvalidRange(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+	root := repo.Head()
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := commitAt(t, repo, start)
+	second := commitAt(t, repo, start.Add(time.Hour))
+
+	err = repo.Squash(ctx, second, first, "")
+	assert.Error(t, err)
+
+	err = repo.Squash(ctx, first, root, "")
+	assert.Error(t, err)
+	assert.Equal(t, second, repo.Head())
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitAt(t, repo, start)
+	commitAt(t, repo, start.Add(10*time.Minute))
+	first := commitAt(t, repo, start.Add(20*time.Minute))
+	commitAt(t, repo, start.Add(time.Hour))
+	second := commitAt(t, repo, start.Add(time.Hour+10*time.Minute))
+	recent := commitAt(t, repo, start.Add(48*time.Hour))
+
+	head, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+
+	repo.clock = func() time.Time { return start.Add(49 * time.Hour) }
+	err = repo.Compact(ctx, CompactionPolicy{KeepRecent: 24 * time.Hour, Interval: time.Hour})
+	require.NoError(t, err)
+
+	times := make([]time.Time, 0)
+	iter := repo.CommitIterator(repo.Head())
+	for !iter.Done() {
+		_, commit, err := iter.Next(ctx)
+		require.NoError(t, err)
+		times = append(times, commit.Time)
+	}
+	require.Len(t, times, 4) // recent commit, two hourly snapshots, and the root commit
+	assert.Equal(t, start.Add(48*time.Hour), times[0])
+	assert.Equal(t, start.Add(time.Hour+10*time.Minute), times[1])
+	assert.Equal(t, start.Add(20*time.Minute), times[2])
+
+	compacted, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+	assert.Equal(t, head.DataRoot, compacted.DataRoot)
+	assert.NotEqual(t, recent, repo.Head())
+
+	removed, err := repo.GC(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, removed, 0)
+
+	_, err = repo.Commit(ctx, first)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.Commit(ctx, second)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGCKeepsOpenTransactions(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	root := repo.Head()
+
+	start := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := commitAt(t, repo, start)
+	commitAt(t, repo, start.Add(time.Hour))
+
+	// the open transactions are based on a commit that is removed from history
+	read, err := repo.ReadTransaction(ctx, first)
+	require.NoError(t, err)
+	tx, err := repo.Transaction(ctx, first)
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	err = repo.Squash(ctx, root, repo.Head(), "Squash")
+	require.NoError(t, err)
+
+	_, err = repo.GC(ctx)
+	require.NoError(t, err)
+	_, err = repo.Commit(ctx, first)
+	require.NoError(t, err)
+	docs, err := read.DocumentIterator(ctx, "User")
+	require.NoError(t, err)
+	for !docs.Done() {
+		_, _, _, err := docs.Next(ctx)
+		require.NoError(t, err)
+	}
+	_, err = tx.Commit(ctx)
+	require.NoError(t, err)
+
+	// objects are removed once the transactions are committed or rolled back
+	require.NoError(t, read.Rollback())
+	removed, err := repo.GC(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, removed, 0)
+	_, err = repo.Commit(ctx, first)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// LengthErrorCode indicates that a value is shorter or longer than allowed.
+	LengthErrorCode = "LENGTH"
+	// RangeErrorCode indicates that a value is outside of the allowed range.
+	RangeErrorCode = "RANGE"
+	// PatternErrorCode indicates that a value does not match the required pattern.
+	PatternErrorCode = "PATTERN"
+	// FormatErrorCode indicates that a value does not conform to the required format.
+	FormatErrorCode = "FORMAT"
+)
+
+const (
+	// emailFormat matches email addresses.
+	emailFormat = "EMAIL"
+	// urlFormat matches absolute URLs.
+	urlFormat = "URL"
+	// uuidFormat matches UUIDs.
+	uuidFormat = "UUID"
+)
+
+// patterns contains compiled pattern directive expressions.
+var patterns sync.Map
+
+// validateConstraints ensures the value satisfies the constraint directives of the field.
+func validateConstraints(path ast.Path, field *ast.FieldDefinition, value any) ValidationErrors {
+	if value == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if dir := field.Directives.ForName("length"); dir != nil {
+		errs = append(errs, validateLength(path, dir, value)...)
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return append(errs, validateScalarConstraints(path, field, value)...)
+	}
+	for i, v := range list {
+		if v == nil {
+			continue
+		}
+		elemPath := append(append(ast.Path{}, path...), ast.PathIndex(i))
+		errs = append(errs, validateScalarConstraints(elemPath, field, v)...)
+	}
+	return errs
+}
+
+// validateScalarConstraints ensures the scalar value satisfies the range, pattern, and format directives of the field.
+func validateScalarConstraints(path ast.Path, field *ast.FieldDefinition, value any) ValidationErrors {
+	var errs ValidationErrors
+	if dir := field.Directives.ForName("range"); dir != nil {
+		errs = append(errs, validateRange(path, dir, value)...)
+	}
+	if dir := field.Directives.ForName("pattern"); dir != nil {
+		errs = append(errs, validatePattern(path, dir, value)...)
+	}
+	if dir := field.Directives.ForName("format"); dir != nil {
+		errs = append(errs, validateFormat(path, dir, value)...)
+	}
+	return errs
+}
+
+func validateLength(path ast.Path, dir *ast.Directive, value any) ValidationErrors {
+	min, max, err := schema_gen.ConstraintBounds(dir)
+	if err != nil {
+		return ValidationErrors{{Path: path, Code: LengthErrorCode, Message: err.Error()}}
+	}
+	var length int
+	switch v := value.(type) {
+	case string:
+		length = utf8.RuneCountInString(v)
+	case []any:
+		length = len(v)
+	default:
+		return nil
+	}
+	if min != nil && float64(length) < *min {
+		return ValidationErrors{{Path: path, Code: LengthErrorCode, Message: fmt.Sprintf("length must be at least %v", *min)}}
+	}
+	if max != nil && float64(length) > *max {
+		return ValidationErrors{{Path: path, Code: LengthErrorCode, Message: fmt.Sprintf("length must be at most %v", *max)}}
+	}
+	return nil
+}
+
+func validateRange(path ast.Path, dir *ast.Directive, value any) ValidationErrors {
+	min, max, err := schema_gen.ConstraintBounds(dir)
+	if err != nil {
+		return ValidationErrors{{Path: path, Code: RangeErrorCode, Message: err.Error()}}
+	}
+	number, ok := coerceFloat(value)
+	if !ok {
+		return nil
+	}
+	if min != nil && number < *min {
+		return ValidationErrors{{Path: path, Code: RangeErrorCode, Message: fmt.Sprintf("value must be at least %v", *min)}}
+	}
+	if max != nil && number > *max {
+		return ValidationErrors{{Path: path, Code: RangeErrorCode, Message: fmt.Sprintf("value must be at most %v", *max)}}
+	}
+	return nil
+}
+
+func validatePattern(path ast.Path, dir *ast.Directive, value any) ValidationErrors {
+	str, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	expr, _ := dir.ArgumentMap(nil)["regex"].(string)
+	re, err := compilePattern(expr)
+	if err != nil {
+		return ValidationErrors{{Path: path, Code: PatternErrorCode, Message: err.Error()}}
+	}
+	if !re.MatchString(str) {
+		return ValidationErrors{{Path: path, Code: PatternErrorCode, Message: fmt.Sprintf("value must match pattern %s", expr)}}
+	}
+	return nil
+}
+
+func validateFormat(path ast.Path, dir *ast.Directive, value any) ValidationErrors {
+	str, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	kind, _ := dir.ArgumentMap(nil)["kind"].(string)
+	if !validFormat(kind, str) {
+		return ValidationErrors{{Path: path, Code: FormatErrorCode, Message: fmt.Sprintf("value must be a valid %s", kind)}}
+	}
+	return nil
+}
+
+// validFormat returns true if the value conforms to the given format kind.
+func validFormat(kind string, value string) bool {
+	switch kind {
+	case emailFormat:
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	case urlFormat:
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	case uuidFormat:
+		return uuid.Validate(value) == nil
+	default:
+		return true
+	}
+}
+
+// compilePattern returns the compiled regular expression, reusing previously compiled expressions.
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(expr, re)
+	return re, nil
+}
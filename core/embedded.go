@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// createEmbedded returns the normalized value of an embedded type.
+func (t *Transaction) createEmbedded(ctx context.Context, def *ast.Definition, value any) (any, error) {
+	input, ok := value.(map[string]any)
+	if !ok {
+		return nil, newValidationError(TypeErrorCode, "expected an object")
+	}
+	out := make(map[string]any)
+	for k, v := range input {
+		field := def.Fields.ForName(k)
+		if field == nil {
+			return nil, &ValidationError{Path: ast.Path{ast.PathName(k)}, Code: TypeErrorCode, Message: "unknown field"}
+		}
+		norm, err := t.createValue(ctx, field.Type, v)
+		if err != nil {
+			return nil, prefixPath(err, ast.PathName(k))
+		}
+		if norm == nil {
+			continue // ignore empty fields
+		}
+		out[k] = norm
+	}
+	for _, field := range def.Fields {
+		if _, ok := out[field.Name]; ok {
+			continue
+		}
+		literal, ok := schema_gen.DefaultValue(field)
+		if !ok {
+			continue
+		}
+		norm, err := t.createValue(ctx, field.Type, literal)
+		if err != nil {
+			return nil, prefixPath(err, ast.PathName(field.Name))
+		}
+		if norm != nil {
+			out[field.Name] = norm
+		}
+	}
+	return out, nil
+}
+
+// patchEmbedded returns the value of an embedded type after applying the patch.
+//
+// The patch either replaces the value using set, removes it using unset,
+// or contains patches for the individual fields of the embedded value.
+func (t *Transaction) patchEmbedded(ctx context.Context, def *ast.Definition, typ *ast.Type, value any, patch any) (any, error) {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return nil, newValidationError(TypeErrorCode, "expected an object")
+	}
+	if set, ok := p[setPatch]; ok && len(p) == 1 {
+		return t.createValue(ctx, typ, set)
+	}
+	if unset, ok := p[unsetPatch]; ok && len(p) == 1 {
+		if unset == true {
+			return nil, nil
+		}
+		return value, nil
+	}
+	current, _ := value.(map[string]any)
+	if current == nil {
+		current = make(map[string]any)
+	}
+	return t.patchDocument(ctx, def, current, p)
+}
+
+// filterEmbedded returns true if the embedded value matches the filter.
+func (t *Transaction) filterEmbedded(ctx context.Context, def *ast.Definition, value any, filter any) (bool, error) {
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return false, nil
+	}
+	return t.filterDocument(ctx, def, "", doc, filter)
+}
+
+// validateEmbedded ensures the embedded value conforms to the given definition.
+func (t *Transaction) validateEmbedded(ctx context.Context, path ast.Path, def *ast.Definition, value any) ValidationErrors {
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: "expected an object"}}
+	}
+	return t.validateFields(ctx, path, def, doc)
+}
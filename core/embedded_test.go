@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedCreateAndPatch(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type Address @embedded {
+		city: String!
+		zip: String
+	}
+	type User {
+		name: String
+		address: Address
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, ok := tx.data.Collections["Address"]
+	assert.False(t, ok)
+
+	var errs ValidationErrors
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"address": map[string]any{"zip": "10115"}})
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, "address.city", errs[0].Path.String())
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"address": map[string]any{"city": "Berlin"}})
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{
+		"address": map[string]any{"zip": map[string]any{"set": "10115"}},
+	})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"city": "Berlin", "zip": "10115"}, doc["address"])
+
+	match, err := tx.FilterDocument(ctx, "User", id, map[string]any{
+		"address": map[string]any{"zip": map[string]any{"eq": "10115"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestEmbeddedMerge(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type Address @embedded {
+		city: String
+		zip: String
+	}
+	type User {
+		address: Address
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"address": map[string]any{"city": "Berlin", "zip": "10115"}})
+	require.NoError(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hash)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = txA.PatchDocument(ctx, "User", id, map[string]any{"address": map[string]any{"city": map[string]any{"set": "Munich"}}})
+	require.NoError(t, err)
+
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = txB.PatchDocument(ctx, "User", id, map[string]any{"address": map[string]any{"zip": map[string]any{"set": "80331"}}})
+	require.NoError(t, err)
+
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hashA)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hashB)
+	require.NoError(t, err)
+
+	txC, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	doc, err := txC.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"city": "Munich", "zip": "80331"}, doc["address"])
+}
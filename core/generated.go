@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// sequenceKey identifies an auto increment field within a collection.
+type sequenceKey struct {
+	collection string
+	field      string
+}
+
+// createGenerated sets the default and generated values of fields missing from a new document.
+func (t *Transaction) createGenerated(ctx context.Context, def *ast.Definition, doc object.Document) error {
+	now := t.repo.clock()
+	for _, field := range def.Fields {
+		if _, ok := doc[field.Name]; ok || !schema_gen.IsGenerated(field) {
+			continue
+		}
+		var value any
+		switch {
+		case field.Directives.ForName("createdAt") != nil, field.Directives.ForName("updatedAt") != nil:
+			value = timestampValue(field.Type, now)
+		case field.Directives.ForName("autoIncrementPerBranch") != nil:
+			seq, err := t.nextSequence(ctx, def.Name, field.Name)
+			if err != nil {
+				return err
+			}
+			value = seq
+		default:
+			literal, _ := schema_gen.DefaultValue(field)
+			norm, err := t.createValue(ctx, field.Type, literal)
+			if err != nil {
+				return prefixPath(err, ast.PathName(field.Name))
+			}
+			value = norm
+		}
+		if value != nil {
+			doc[field.Name] = value
+		}
+	}
+	return nil
+}
+
+// patchGenerated sets the updated timestamp fields of a patched document not set by the patch.
+func (t *Transaction) patchGenerated(def *ast.Definition, doc object.Document, patch map[string]any) {
+	now := t.repo.clock()
+	for _, field := range def.Fields {
+		if _, ok := patch[field.Name]; ok || field.Directives.ForName("updatedAt") == nil {
+			continue
+		}
+		doc[field.Name] = timestampValue(field.Type, now)
+	}
+}
+
+// timestampValue returns the time in the representation used by the given type.
+func timestampValue(typ *ast.Type, now time.Time) any {
+	switch typ.NamedType {
+	case "Int":
+		return now.UnixMilli()
+	case dateTimeScalar:
+		return now.UTC()
+	default:
+		return now.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// nextSequence returns the next auto increment value of the field.
+//
+// Sequences start after the largest value in the collection of this branch.
+func (t *Transaction) nextSequence(ctx context.Context, collection, field string) (int64, error) {
+	key := sequenceKey{collection, field}
+	if t.sequences == nil {
+		t.sequences = make(map[sequenceKey]int64)
+	}
+	last, ok := t.sequences[key]
+	if !ok {
+		iter, err := t.DocumentIterator(ctx, collection)
+		if err != nil {
+			return 0, err
+		}
+		for !iter.Done() {
+			_, _, doc, err := iter.Next(ctx)
+			if err != nil {
+				return 0, err
+			}
+			if v, ok := doc[field].(int64); ok && v > last {
+				last = v
+			}
+		}
+	}
+	t.sequences[key] = last + 1
+	return last + 1, nil
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedDefaultValues(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User {
+		name: String! @default(value: "anonymous")
+		score: Float @default(value: 1)
+		role: String @default(value: "MEMBER")
+		tags: [String] @default(value: ["new"])
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"role": "ADMIN"})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+
+	expect := map[string]any{
+		"name":  "anonymous",
+		"score": float64(1),
+		"role":  "ADMIN",
+		"tags":  []any{"new"},
+	}
+	assert.Equal(t, expect, doc)
+}
+
+func TestGeneratedTimestamps(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User {
+		name: String
+		created: String @createdAt
+		updated: Int @updatedAt
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.clock = func() time.Time { return now }
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", doc["created"])
+	assert.Equal(t, now.UnixMilli(), doc["updated"])
+
+	now = now.Add(time.Hour)
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": "Alice"}})
+	require.NoError(t, err)
+
+	doc, err = tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", doc["created"])
+	assert.Equal(t, now.UnixMilli(), doc["updated"])
+}
+
+func TestGeneratedAutoIncrement(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Ticket { number: Int! @autoIncrementPerBranch }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		id, err := tx.CreateDocument(ctx, "Ticket", map[string]any{})
+		require.NoError(t, err)
+
+		doc, err := tx.ReadDocument(ctx, "Ticket", id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), doc["number"])
+	}
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	tx, err = repo.Transaction(ctx, hash)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Ticket", map[string]any{})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "Ticket", id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), doc["number"])
+}
+
+func TestGeneratedAutoIncrementConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Ticket { number: Int! @autoIncrementPerBranch }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	_, err = txA.CreateDocument(ctx, "Ticket", map[string]any{})
+	require.NoError(t, err)
+	_, err = txB.CreateDocument(ctx, "Ticket", map[string]any{})
+	require.NoError(t, err)
+
+	_, err = repo.CommitTransaction(ctx, txA, CommitInfo{})
+	require.NoError(t, err)
+	// both transactions assigned the same number on the same branch
+	_, err = repo.CommitTransaction(ctx, txB, CommitInfo{})
+	var conflict *UniqueConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []string{"number"}, conflict.Fields)
+}
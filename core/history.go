@@ -0,0 +1,201 @@
+package core
+
+import (
+	"context"
+
+	"github.com/rodent-software/capy/object"
+)
+
+// DocumentVersion is a snapshot of a document created by a commit.
+type DocumentVersion struct {
+	// Commit is the hash of the commit that created the version.
+	Commit object.Hash
+	// Hash is the hash of the document or nil if the commit deleted it.
+	Hash object.Hash
+	// Document is the value of the document or nil if the commit deleted it.
+	Document object.Document
+}
+
+// historyWalker loads the versions of a single document from commits.
+type historyWalker struct {
+	repo       *Repository
+	collection string
+	id         string
+	// hashes caches the document hash of each visited commit.
+	hashes map[string]object.Hash
+	// docs caches decoded documents by hash.
+	docs map[string]object.Document
+}
+
+// newHistoryWalker returns a walker for the document in the given collection with the matching id.
+func (r *Repository) newHistoryWalker(collection, id string) *historyWalker {
+	return &historyWalker{
+		repo:       r,
+		collection: collection,
+		id:         id,
+		hashes:     make(map[string]object.Hash),
+		docs:       make(map[string]object.Document),
+	}
+}
+
+// documentHash returns the hash of the document in the commit or nil if it does not exist.
+func (w *historyWalker) documentHash(ctx context.Context, hash object.Hash) (object.Hash, error) {
+	if doc, ok := w.hashes[hash.String()]; ok {
+		return doc, nil
+	}
+	commit, err := w.repo.Commit(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	dataRoot, err := w.repo.DataRoot(ctx, commit.DataRoot)
+	if err != nil {
+		return nil, err
+	}
+	var doc object.Hash
+	if colHash, ok := dataRoot.Collections[w.collection]; ok {
+		col, err := w.repo.Collection(ctx, colHash)
+		if err != nil {
+			return nil, err
+		}
+		doc = col.Documents[w.id]
+	}
+	w.hashes[hash.String()] = doc
+	return doc, nil
+}
+
+// document returns the document in the commit or nil if it does not exist.
+func (w *historyWalker) document(ctx context.Context, hash object.Hash) (object.Document, error) {
+	docHash, err := w.documentHash(ctx, hash)
+	if err != nil || docHash == nil {
+		return nil, err
+	}
+	if doc, ok := w.docs[docHash.String()]; ok {
+		return doc, nil
+	}
+	doc, err := w.repo.Document(ctx, docHash)
+	if err != nil {
+		return nil, err
+	}
+	w.docs[docHash.String()] = doc
+	return doc, nil
+}
+
+// changed returns true if the document in the commit differs from the document in all of its parents.
+func (w *historyWalker) changed(ctx context.Context, hash object.Hash, commit *object.Commit) (bool, error) {
+	doc, err := w.documentHash(ctx, hash)
+	if err != nil {
+		return false, err
+	}
+	if len(commit.Parents) == 0 {
+		return doc != nil, nil
+	}
+	for _, p := range commit.Parents {
+		parent, err := w.documentHash(ctx, p)
+		if err != nil {
+			return false, err
+		}
+		if parent.Equal(doc) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// DocumentHistory returns the versions of the document in the given collection with the matching id.
+//
+// Versions are returned in the order their commits are visited starting from the repo head.
+func (r *Repository) DocumentHistory(ctx context.Context, collection, id string) ([]DocumentVersion, error) {
+	return r.documentHistory(ctx, r.Head(), collection, id)
+}
+
+// DocumentHistory returns the versions of the document in the given collection with the matching id.
+//
+// Versions are returned in the order their commits are visited starting from the transaction commit.
+func (t *Transaction) DocumentHistory(ctx context.Context, collection, id string) ([]DocumentVersion, error) {
+	return t.repo.documentHistory(ctx, t.hash, collection, id)
+}
+
+func (r *Repository) documentHistory(ctx context.Context, hash object.Hash, collection, id string) ([]DocumentVersion, error) {
+	walker := r.newHistoryWalker(collection, id)
+	versions := make([]DocumentVersion, 0)
+	iter := r.CommitIterator(hash)
+	for !iter.Done() {
+		hash, commit, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := walker.changed(ctx, hash, commit)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue // document is unchanged
+		}
+		docHash, err := walker.documentHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := walker.document(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, DocumentVersion{Commit: hash, Hash: docHash, Document: doc})
+	}
+	return versions, nil
+}
+
+// DocumentBlame returns a mapping of the document fields to the hash of the commit that last set their value.
+//
+// Fields are traced through the parents of each commit until a parent with a different value is found.
+func (r *Repository) DocumentBlame(ctx context.Context, collection, id string) (map[string]object.Hash, error) {
+	return r.documentBlame(ctx, r.Head(), collection, id)
+}
+
+// DocumentBlame returns a mapping of the document fields to the hash of the commit that last set their value.
+//
+// Fields are traced through the parents of each commit until a parent with a different value is found.
+func (t *Transaction) DocumentBlame(ctx context.Context, collection, id string) (map[string]object.Hash, error) {
+	return t.repo.documentBlame(ctx, t.hash, collection, id)
+}
+
+func (r *Repository) documentBlame(ctx context.Context, hash object.Hash, collection, id string) (map[string]object.Hash, error) {
+	walker := r.newHistoryWalker(collection, id)
+	doc, err := walker.document(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	blame := make(map[string]object.Hash, len(doc))
+	for field, value := range doc {
+		commit, err := walker.blameField(ctx, hash, field, value)
+		if err != nil {
+			return nil, err
+		}
+		blame[field] = commit
+	}
+	return blame, nil
+}
+
+// blameField returns the hash of the commit that set the field of the document to the value.
+func (w *historyWalker) blameField(ctx context.Context, hash object.Hash, field string, value any) (object.Hash, error) {
+	for {
+		commit, err := w.repo.Commit(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		var next object.Hash
+		for _, p := range commit.Parents {
+			parent, err := w.document(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			if parent != nil && valuesEqual(parent[field], value) {
+				next = p
+				break
+			}
+		}
+		if next == nil {
+			return hash, nil
+		}
+		hash = next
+	}
+}
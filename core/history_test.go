@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentHistoryAndBlame(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String age: Int } type Post { title: String }`)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob", "age": int64(1)})
+	require.NoError(t, err)
+
+	created, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, created))
+
+	// unrelated commits are skipped
+	tx, err = repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"title": "hello"})
+	require.NoError(t, err)
+
+	unrelated, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, unrelated))
+
+	tx, err = repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"age": map[string]any{"set": int64(2)}})
+	require.NoError(t, err)
+
+	patched, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, patched))
+
+	versions, err := repo.DocumentHistory(ctx, "User", id)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, patched, versions[0].Commit)
+	assert.Equal(t, int64(2), versions[0].Document["age"])
+	assert.Equal(t, created, versions[1].Commit)
+	assert.Equal(t, int64(1), versions[1].Document["age"])
+
+	blame, err := repo.DocumentBlame(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, created, blame["name"])
+	assert.Equal(t, patched, blame["age"])
+
+	tx, err = repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "User", id)
+	require.NoError(t, err)
+
+	deleted, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, deleted))
+
+	versions, err = repo.DocumentHistory(ctx, "User", id)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, deleted, versions[0].Commit)
+	assert.Nil(t, versions[0].Document)
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/rodent-software/capy/object"
+
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// uuidV4Strategy generates random version 4 UUIDs.
+	uuidV4Strategy = "UUID_V4"
+	// uuidV7Strategy generates time ordered version 7 UUIDs.
+	uuidV7Strategy = "UUID_V7"
+	// hashStrategy derives ids from the content hash of the key fields.
+	hashStrategy = "HASH"
+)
+
+// idStrategy returns the id strategy and key fields of the given collection definition.
+func idStrategy(def *ast.Definition) (string, []string) {
+	dir := def.Directives.ForName("id")
+	if dir == nil {
+		return uuidV4Strategy, nil
+	}
+	args := dir.ArgumentMap(nil)
+	strategy, _ := args["strategy"].(string)
+	list, _ := args["fields"].([]any)
+	fields := make([]string, len(list))
+	for i, f := range list {
+		fields[i] = f.(string)
+	}
+	return strategy, fields
+}
+
+// contentIDs returns true if the ids of the collection are derived from the document contents.
+//
+// Documents created with the same content id have the same key fields.
+func contentIDs(def *ast.Definition) bool {
+	if def == nil {
+		return false
+	}
+	strategy, _ := idStrategy(def)
+	return strategy == hashStrategy
+}
+
+// documentID returns the id for a new document using the id strategy of the collection.
+func documentID(def *ast.Definition, doc object.Document) (string, error) {
+	strategy, fields := idStrategy(def)
+	switch strategy {
+	case uuidV4Strategy:
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return "", err
+		}
+		return id.String(), nil
+	case uuidV7Strategy:
+		id, err := uuid.NewV7()
+		if err != nil {
+			return "", err
+		}
+		return id.String(), nil
+	case hashStrategy:
+		key := map[string]any(doc)
+		if len(fields) > 0 {
+			key = make(map[string]any, len(fields))
+		}
+		for _, f := range fields {
+			v, ok := doc[f]
+			if !ok {
+				return "", fmt.Errorf("id field %s is required", f)
+			}
+			key[f] = v
+		}
+		hash, err := hashObject(key)
+		if err != nil {
+			return "", err
+		}
+		return hash.String(), nil
+	default:
+		return "", fmt.Errorf("invalid id strategy %s", strategy)
+	}
+}
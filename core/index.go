@@ -0,0 +1,219 @@
+package core
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// documentKey uniquely identifies a document within a data root.
+type documentKey struct {
+	collection string
+	id         string
+}
+
+// referenceIndex is a reverse index of relation fields.
+//
+// The index is built from the transaction data root the first time it is
+// used and is maintained as documents are written and removed.
+// Indexes are shared with other transactions by the hash of their data root
+// and are copied before they are modified.
+type referenceIndex struct {
+	// targets maps documents to the relation fields that reference them.
+	targets map[documentKey][]reference
+	// sources maps documents to the documents they reference.
+	sources map[documentKey][]documentKey
+}
+
+// indexCacheSize is the number of data root indexes shared by the transactions of a repo.
+const indexCacheSize = 8
+
+// indexKey returns the key of the named index of the current transaction data root.
+func (t *Transaction) indexKey(ctx context.Context, name string) (string, error) {
+	err := t.encodeCollections(ctx)
+	if err != nil {
+		return "", err
+	}
+	hash, err := hashObject(t.data)
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + hash.String(), nil
+}
+
+// shareIndexes stores the indexes of the transaction in the repo by the hash of the committed data root.
+//
+// Transactions based on the commit use the indexes instead of rebuilding them.
+func (t *Transaction) shareIndexes(data object.Hash) {
+	if t.refs != nil {
+		t.repo.indexes.add("refs:"+data.String(), t.refs, 1)
+		t.sharedRefs = true
+	}
+	if t.uniques != nil {
+		t.repo.indexes.add("uniques:"+data.String(), t.uniques, 1)
+		t.sharedUniques = true
+	}
+}
+
+// referenceIndex returns the reverse index of relation fields, building it if necessary.
+func (t *Transaction) referenceIndex(ctx context.Context) (*referenceIndex, error) {
+	if t.refs != nil {
+		return t.refs, nil
+	}
+	key, err := t.indexKey(ctx, "refs")
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := t.repo.indexes.get(key); ok {
+		t.refs, t.sharedRefs = cached.(*referenceIndex), true
+		return t.refs, nil
+	}
+	index := &referenceIndex{
+		targets: make(map[documentKey][]reference),
+		sources: make(map[documentKey][]documentKey),
+	}
+	for collection := range t.data.Collections {
+		if !t.hasRelations(collection) {
+			continue
+		}
+		iter, err := t.DocumentIterator(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		for !iter.Done() {
+			id, _, doc, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			t.indexDocument(index, collection, id, doc)
+		}
+	}
+	t.repo.indexes.add(key, index, 1)
+	t.refs, t.sharedRefs = index, true
+	return index, nil
+}
+
+// hasRelations returns true if the collection contains stored relation fields.
+func (t *Transaction) hasRelations(collection string) bool {
+	def := t.schema.Types[collection]
+	for _, field := range def.Fields {
+		if t.isRelation(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexDocument adds the relation fields of the document to the index.
+func (t *Transaction) indexDocument(index *referenceIndex, collection, id string, doc object.Document) {
+	source := documentKey{collection, id}
+	def := t.schema.Types[collection]
+	for _, field := range def.Fields {
+		if !t.isRelation(field) {
+			continue
+		}
+		for _, key := range t.relationKeys(field, doc[field.Name]) {
+			index.targets[key] = append(index.targets[key], reference{collection: collection, id: id, field: field})
+			index.sources[source] = append(index.sources[source], key)
+		}
+	}
+}
+
+// clone returns a copy of the index that can be modified independently.
+func (index *referenceIndex) clone() *referenceIndex {
+	out := &referenceIndex{
+		targets: make(map[documentKey][]reference, len(index.targets)),
+		sources: make(map[documentKey][]documentKey, len(index.sources)),
+	}
+	for key, refs := range index.targets {
+		out.targets[key] = slices.Clone(refs)
+	}
+	for key, keys := range index.sources {
+		out.sources[key] = slices.Clone(keys)
+	}
+	return out
+}
+
+// unindexDocument removes the relation fields of the document from the index.
+func (index *referenceIndex) unindexDocument(collection, id string) {
+	source := documentKey{collection, id}
+	for _, key := range index.sources[source] {
+		index.targets[key] = slices.DeleteFunc(index.targets[key], func(r reference) bool {
+			return r.is(collection, id)
+		})
+		if len(index.targets[key]) == 0 {
+			delete(index.targets, key)
+		}
+	}
+	delete(index.sources, source)
+}
+
+// updateIndexes updates the transaction indexes after a document is written or removed.
+//
+// A nil document indicates that the document was removed.
+func (t *Transaction) updateIndexes(collection, id string, doc object.Document) error {
+	if t.refs != nil {
+		if t.sharedRefs {
+			t.refs, t.sharedRefs = t.refs.clone(), false
+		}
+		t.refs.unindexDocument(collection, id)
+		if doc != nil {
+			t.indexDocument(t.refs, collection, id, doc)
+		}
+	}
+	if t.uniques != nil {
+		if t.sharedUniques {
+			t.uniques, t.sharedUniques = t.uniques.clone(), false
+		}
+		t.uniques.unindexDocument(collection, id)
+		if doc == nil {
+			return nil
+		}
+		keys, err := uniqueKeys(t.schema.Types[collection], doc)
+		if err != nil {
+			return err
+		}
+		t.uniques.indexDocument(collection, id, keys)
+	}
+	return nil
+}
+
+// relationKeys returns the keys of the documents referenced by the relation field value.
+func (t *Transaction) relationKeys(field *ast.FieldDefinition, value any) []documentKey {
+	ids := relationIDs(value)
+	keys := make([]documentKey, 0, len(ids))
+	if !schema_gen.IsAbstract(t.schema.Types[field.Type.Name()]) {
+		for _, id := range ids {
+			keys = append(keys, documentKey{field.Type.Name(), id})
+		}
+		return keys
+	}
+	for _, ref := range ids {
+		if collection, id, ok := ParseReference(ref); ok {
+			keys = append(keys, documentKey{collection, id})
+		}
+	}
+	return keys
+}
+
+// relationIDs returns the ids referenced by the relation value.
+func relationIDs(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		ids := make([]string, 0, len(v))
+		for _, e := range v {
+			if id, ok := e.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	default:
+		return nil
+	}
+}
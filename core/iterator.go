@@ -2,17 +2,18 @@ package core
 
 import (
 	"context"
-	"fmt"
+	"maps"
 	"slices"
 
 	"github.com/rodent-software/capy/object"
 )
 
 type CommitIterator struct {
-	repo *Repository
-	next []object.Hash
-	seen map[string]struct{}
-	prev int
+	repo        *Repository
+	next        []object.Hash
+	seen        map[string]struct{}
+	prev        int
+	firstParent bool
 }
 
 // CommitIterator returns a new iterator that can be used to iterate through all parents of a commit.
@@ -38,6 +39,14 @@ func (i *CommitIterator) Done() bool {
 	return len(i.next) == 0
 }
 
+// FirstParent restricts the iterator to the first parent of each commit.
+//
+// This visits the commits of the current branch without the commits of merged branches.
+func (i *CommitIterator) FirstParent() *CommitIterator {
+	i.firstParent = true
+	return i
+}
+
 // Skip skips the parents of the last node visited by the iterator.
 func (i *CommitIterator) Skip() {
 	i.next = i.next[:i.prev]
@@ -54,7 +63,11 @@ func (i *CommitIterator) Next(ctx context.Context) (object.Hash, *object.Commit,
 	if err != nil {
 		return nil, nil, err
 	}
-	for _, p := range commit.Parents {
+	parents := commit.Parents
+	if i.firstParent && len(parents) > 1 {
+		parents = parents[:1]
+	}
+	for _, p := range parents {
 		_, ok := i.seen[p.String()]
 		if ok {
 			continue
@@ -67,18 +80,14 @@ func (i *CommitIterator) Next(ctx context.Context) (object.Hash, *object.Commit,
 
 // DocumentIterator iterates over all documents in a collection.
 type DocumentIterator struct {
-	repo *Repository
+	tx   *Transaction
 	keys []string
 	docs map[string]object.Hash
 }
 
 // NewDocumentIterator returns a new iterator that can be used to iterate through all documents in a collection.
 func (t *Transaction) DocumentIterator(ctx context.Context, collection string) (*DocumentIterator, error) {
-	hash, ok := t.data.Collections[collection]
-	if !ok {
-		return nil, fmt.Errorf("collection does not exist: %s", collection)
-	}
-	col, err := t.repo.Collection(ctx, hash)
+	col, err := t.collection(ctx, collection)
 	if err != nil {
 		return nil, err
 	}
@@ -88,9 +97,9 @@ func (t *Transaction) DocumentIterator(ctx context.Context, collection string) (
 	}
 	slices.Sort(keys)
 	return &DocumentIterator{
-		repo: t.repo,
+		tx:   t,
 		keys: keys,
-		docs: col.Documents,
+		docs: maps.Clone(col.Documents),
 	}, nil
 }
 
@@ -105,7 +114,7 @@ func (i *DocumentIterator) Next(ctx context.Context) (string, object.Hash, map[s
 	val := i.docs[key]
 	i.keys = i.keys[1:]
 
-	doc, err := i.repo.Document(ctx, val)
+	doc, err := loadDocument(ctx, i.tx, val)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -2,9 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/rodent-software/capy/graphql/schema_gen"
 	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 // MergeConflictResolver is a callback function that is used to resolver merge conflicts.
@@ -20,25 +24,90 @@ var OursConflictResolver MergeConflictResolver = func(ctx context.Context, base,
 	return ours, nil
 }
 
+// ErrorConflictResolver is a merge strategy that fails with a MergeConflictError when changes conflict.
+var ErrorConflictResolver MergeConflictResolver = func(ctx context.Context, base, ours, theirs any) (any, error) {
+	return nil, &MergeConflictError{}
+}
+
+// MergeConflictError is returned when a value was changed on both sides of a merge.
+type MergeConflictError struct {
+	// Collection is the name of the collection containing the conflict.
+	Collection string
+	// ID is the id of the document containing the conflict.
+	ID string
+	// Path is the path to the conflicting value or empty if the document
+	// was deleted on one side and changed on the other, or created on both sides.
+	Path ast.Path
+}
+
+func (e *MergeConflictError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("merge conflict in %s document %s", e.Collection, e.ID)
+	}
+	return fmt.Sprintf("merge conflict in %s document %s at %s", e.Collection, e.ID, e.Path)
+}
+
 // Merge attempts to Merge the commit with the given hash into the current head.
 func (r *Repository) Merge(ctx context.Context, hash object.Hash) error {
-	bases, err := r.mergeBase(ctx, r.head, hash)
+	r.update.Lock()
+	defer r.update.Unlock()
+	return r.merge(ctx, r.conflict, hash)
+}
+
+// CommitTransaction commits the transaction and merges it into the current head.
+//
+// Transactions use snapshot isolation: they read the commit they were created from
+// and do not observe changes committed concurrently. If the head moved since then,
+// the changes are merged into the head and a MergeConflictError or UniqueConflictError
+// is returned if they conflict with the concurrent changes. The head is unchanged
+// when an error is returned, and the transaction can be retried on the new head.
+// The objects of the transaction are no longer kept by GC once it is merged.
+//
+// The commit is written and merged while head updates are blocked so that GC
+// cannot remove its objects before they are reachable from the head.
+func (r *Repository) CommitTransaction(ctx context.Context, tx *Transaction, info CommitInfo) (object.Hash, error) {
+	r.update.Lock()
+	defer r.update.Unlock()
+	hash, err := tx.commit(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	err = r.merge(ctx, ErrorConflictResolver, hash)
+	if err != nil {
+		return nil, err
+	}
+	tx.releaseRoots()
+	return r.Head(), nil
+}
+
+// IsConflict returns true if the error was caused by changes that conflict with the repo head.
+func IsConflict(err error) bool {
+	var mergeConflict *MergeConflictError
+	var uniqueConflict *UniqueConflictError
+	return errors.As(err, &mergeConflict) || errors.As(err, &uniqueConflict)
+}
+
+// merge merges the commit with the given hash into the current head using the resolver.
+//
+// Callers must hold the update lock.
+func (r *Repository) merge(ctx context.Context, resolve MergeConflictResolver, hash object.Hash) error {
+	head := r.Head()
+	bases, err := r.mergeBase(ctx, head, hash)
 	if err != nil {
 		return err
 	}
 	if len(bases) == 0 {
 		return fmt.Errorf("no merge base found")
 	}
-	head, err := r.mergeCommits(ctx, bases[0], r.head, hash)
+	merged, err := r.mergeCommits(ctx, resolve, bases[0], head, hash)
 	if err != nil {
 		return err
 	}
-	r.head = head
-	return nil
+	return r.setHead(ctx, merged)
 }
 
 // mergeCommits returns the results of a three way merge between the given commit hashes.
-func (r *Repository) mergeCommits(ctx context.Context, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
+func (r *Repository) mergeCommits(ctx context.Context, resolve MergeConflictResolver, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
 	if theirHash.Equal(baseHash) {
 		return ourHash, nil
 	}
@@ -57,18 +126,30 @@ func (r *Repository) mergeCommits(ctx context.Context, baseHash, ourHash, theirH
 	if err != nil {
 		return nil, err
 	}
-	dataRoot, err := r.mergeDataRoots(ctx, base.DataRoot, ours.DataRoot, theirs.DataRoot)
+	m := &merger{repo: r, objects: r, resolve: resolve}
+	dataRoot, err := m.mergeDataRoots(ctx, base.DataRoot, ours.DataRoot, theirs.DataRoot)
 	if err != nil {
 		return nil, err
 	}
 	commit := &object.Commit{
 		Parents:  []object.Hash{ourHash, theirHash},
 		DataRoot: dataRoot,
+		Time:     r.clock().UTC(),
 	}
 	return EncodeObject(ctx, r.storage, commit)
 }
 
-func (r *Repository) mergeDataRoots(ctx context.Context, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
+// merger performs three way merges of the objects in a store.
+type merger struct {
+	repo *Repository
+	// objects contains the merged objects and receives the merge results.
+	objects objectStore
+	// resolve is used to resolve conflicting values.
+	resolve MergeConflictResolver
+}
+
+// mergeDataRoots returns the results of a three way merge between the given data roots.
+func (m *merger) mergeDataRoots(ctx context.Context, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
 	if theirHash.Equal(baseHash) && ourHash.Equal(baseHash) {
 		return baseHash, nil
 	}
@@ -78,15 +159,15 @@ func (r *Repository) mergeDataRoots(ctx context.Context, baseHash, ourHash, thei
 	if ourHash.Equal(baseHash) {
 		return theirHash, nil
 	}
-	base, err := r.DataRoot(ctx, baseHash)
+	base, err := loadDataRoot(ctx, m.objects, baseHash)
 	if err != nil {
 		return nil, err
 	}
-	ours, err := r.DataRoot(ctx, ourHash)
+	ours, err := loadDataRoot(ctx, m.objects, ourHash)
 	if err != nil {
 		return nil, err
 	}
-	theirs, err := r.DataRoot(ctx, theirHash)
+	theirs, err := loadDataRoot(ctx, m.objects, theirHash)
 	if err != nil {
 		return nil, err
 	}
@@ -100,39 +181,102 @@ func (r *Repository) mergeDataRoots(ctx context.Context, baseHash, ourHash, thei
 	for k := range theirs.Collections {
 		keys[k] = struct{}{}
 	}
+	schemaHash, schema, err := m.repo.mergeSchemas(ctx, base, ours, theirs)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
 	collections := make(map[string]object.Hash)
 	for k := range keys {
-		hash, err := r.mergeCollections(ctx, base.Collections[k], ours.Collections[k], theirs.Collections[k])
+		hash, err := m.mergeCollections(ctx, schema, schema.Types[k], base.Collections[k], ours.Collections[k], theirs.Collections[k])
+		var conflict *MergeConflictError
+		if errors.As(err, &conflict) && conflict.Collection == "" {
+			conflict.Collection = k
+		}
 		if err != nil {
 			return nil, err
 		}
+		if hash == nil {
+			continue // collection was removed
+		}
 		collections[k] = hash
+		if !ours.Collections[k].Equal(base.Collections[k]) && !theirs.Collections[k].Equal(base.Collections[k]) {
+			changed = append(changed, k)
+		}
 	}
 	dataRoot := &object.DataRoot{
 		Collections: collections,
+		Schema:      schemaHash,
+	}
+	// collections changed on both sides may contain unique constraint violations
+	tx := &Transaction{repo: m.repo, schema: schema, data: dataRoot}
+	if t, ok := m.objects.(*Transaction); ok {
+		tx.objects = t.objects // merged objects are buffered by the transaction
+	}
+	_, err = tx.buildUniqueIndex(ctx, changed)
+	if err != nil {
+		return nil, err
+	}
+	return m.objects.storeObject(ctx, dataRoot)
+}
+
+// mergeSchemas returns the schema of the merged data roots.
+//
+// Schemas changed on one side are only merged if the change does not require a migration.
+func (r *Repository) mergeSchemas(ctx context.Context, base, ours, theirs *object.DataRoot) (object.Hash, *ast.Schema, error) {
+	var changed *object.DataRoot
+	switch {
+	case ours.Schema.Equal(theirs.Schema), theirs.Schema.Equal(base.Schema):
+		changed = ours
+	case ours.Schema.Equal(base.Schema):
+		changed = theirs
+	default:
+		return nil, nil, fmt.Errorf("merge conflict: schemas changed on both sides")
+	}
+	if !changed.Schema.Equal(base.Schema) {
+		baseInput, err := r.inputSchema(ctx, base)
+		if err != nil {
+			return nil, nil, err
+		}
+		changedInput, err := r.inputSchema(ctx, changed)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = diffSchemas(baseInput, changedInput, &Migration{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge conflict: %w", err)
+		}
 	}
-	return EncodeObject(ctx, r.storage, dataRoot)
+	schema, err := r.dataSchema(ctx, changed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return changed.Schema, schema, nil
 }
 
-func (r *Repository) mergeCollections(ctx context.Context, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
+// mergeCollections returns the results of a three way merge between the given collections of documents of type def.
+func (m *merger) mergeCollections(ctx context.Context, schema *ast.Schema, def *ast.Definition, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
 	if theirHash.Equal(baseHash) && ourHash.Equal(baseHash) {
 		return baseHash, nil
 	}
+	if ourHash.Equal(theirHash) {
+		return ourHash, nil
+	}
 	if theirHash.Equal(baseHash) {
 		return ourHash, nil
 	}
 	if ourHash.Equal(baseHash) {
 		return theirHash, nil
 	}
-	base, err := r.Collection(ctx, baseHash)
+	base, err := loadCollection(ctx, m.objects, baseHash)
 	if err != nil {
 		return nil, err
 	}
-	ours, err := r.Collection(ctx, ourHash)
+	ours, err := loadCollection(ctx, m.objects, ourHash)
 	if err != nil {
 		return nil, err
 	}
-	theirs, err := r.Collection(ctx, theirHash)
+	theirs, err := loadCollection(ctx, m.objects, theirHash)
 	if err != nil {
 		return nil, err
 	}
@@ -148,19 +292,29 @@ func (r *Repository) mergeCollections(ctx context.Context, baseHash, ourHash, th
 	}
 	documents := make(map[string]object.Hash)
 	for k := range keys {
-		hash, err := r.mergeDocuments(ctx, base.Documents[k], ours.Documents[k], theirs.Documents[k])
+		hash, err := m.mergeDocuments(ctx, schema, def, base.Documents[k], ours.Documents[k], theirs.Documents[k])
+		var conflict *MergeConflictError
+		if errors.As(err, &conflict) && conflict.ID == "" {
+			conflict.ID = k
+		}
 		if err != nil {
 			return nil, err
 		}
+		if hash == nil {
+			continue // document was deleted
+		}
 		documents[k] = hash
 	}
 	collection := &object.Collection{
 		Documents: documents,
 	}
-	return EncodeObject(ctx, r.storage, collection)
+	return m.objects.storeObject(ctx, collection)
 }
 
-func (r *Repository) mergeDocuments(ctx context.Context, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
+// mergeDocuments returns the results of a three way merge between the given documents.
+//
+// Nil hashes represent documents that do not exist, and nil is returned if the merged document is deleted.
+func (m *merger) mergeDocuments(ctx context.Context, schema *ast.Schema, def *ast.Definition, baseHash, ourHash, theirHash object.Hash) (object.Hash, error) {
 	if theirHash.Equal(baseHash) && ourHash.Equal(baseHash) {
 		return baseHash, nil
 	}
@@ -170,18 +324,77 @@ func (r *Repository) mergeDocuments(ctx context.Context, baseHash, ourHash, thei
 	if ourHash.Equal(baseHash) {
 		return theirHash, nil
 	}
-	base, err := r.Document(ctx, baseHash)
+	if ourHash.Equal(theirHash) {
+		return ourHash, nil
+	}
+	base, err := m.optionalDocument(ctx, baseHash)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := m.optionalDocument(ctx, ourHash)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := m.optionalDocument(ctx, theirHash)
 	if err != nil {
 		return nil, err
 	}
-	ours, err := r.Document(ctx, ourHash)
+	if ours == nil || theirs == nil {
+		// deleted on one side and changed on the other
+		return m.resolveDocument(ctx, base, ours, theirs)
+	}
+	if base == nil && !contentIDs(def) {
+		// created on both sides with the same id
+		return m.resolveDocument(ctx, base, ours, theirs)
+	}
+	document, err := m.mergeMaps(ctx, schema, def, base, ours, theirs)
 	if err != nil {
 		return nil, err
 	}
-	theirs, err := r.Document(ctx, theirHash)
+	return m.objects.storeObject(ctx, object.Document(document))
+}
+
+// optionalDocument returns the document with the given hash or nil if the hash is nil.
+func (m *merger) optionalDocument(ctx context.Context, hash object.Hash) (object.Document, error) {
+	if hash == nil {
+		return nil, nil
+	}
+	return loadDocument(ctx, m.objects, hash)
+}
+
+// resolveDocument returns the hash of the document chosen by the resolver or nil if it chose a deleted document.
+func (m *merger) resolveDocument(ctx context.Context, base, ours, theirs object.Document) (object.Hash, error) {
+	value, err := m.resolve(ctx, documentValue(base), documentValue(ours), documentValue(theirs))
 	if err != nil {
 		return nil, err
 	}
+	var doc object.Document
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case object.Document:
+		doc = v
+	case map[string]any:
+		doc = v
+	default:
+		return nil, fmt.Errorf("invalid resolved document %T", value)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	return m.objects.storeObject(ctx, doc)
+}
+
+// documentValue returns the document as a resolver value where missing documents are nil.
+func documentValue(doc object.Document) any {
+	if doc == nil {
+		return nil
+	}
+	return map[string]any(doc)
+}
+
+// mergeMaps returns the results of a three way merge of each field in the given values of type def.
+func (m *merger) mergeMaps(ctx context.Context, schema *ast.Schema, def *ast.Definition, base, ours, theirs map[string]any) (map[string]any, error) {
 	keys := make(map[string]struct{})
 	for k := range base {
 		keys[k] = struct{}{}
@@ -192,28 +405,80 @@ func (r *Repository) mergeDocuments(ctx context.Context, baseHash, ourHash, thei
 	for k := range theirs {
 		keys[k] = struct{}{}
 	}
-	document := object.NewDocument()
+	result := make(map[string]any, len(keys))
 	for k := range keys {
-		prop, err := r.mergeProperty(ctx, base[k], ours[k], theirs[k])
+		var field *ast.FieldDefinition
+		if def != nil {
+			field = def.Fields.ForName(k)
+		}
+		prop, err := m.mergeProperty(ctx, schema, field, base[k], ours[k], theirs[k])
+		var conflict *MergeConflictError
+		if errors.As(err, &conflict) {
+			conflict.Path = append(ast.Path{ast.PathName(k)}, conflict.Path...)
+		}
 		if err != nil {
 			return nil, err
 		}
-		document[k] = prop
+		if prop == nil {
+			continue // ignore removed properties
+		}
+		result[k] = prop
 	}
-	return EncodeObject(ctx, r.storage, document)
+	return result, nil
 }
 
-func (r *Repository) mergeProperty(ctx context.Context, base, ours, theirs any) (any, error) {
-	if theirs == base && ours == base {
+// mergeProperty returns the results of a three way merge of the given values of the field.
+//
+// Values of fields without a definition are resolved as a whole.
+func (m *merger) mergeProperty(ctx context.Context, schema *ast.Schema, field *ast.FieldDefinition, base, ours, theirs any) (any, error) {
+	if valuesEqual(theirs, base) && valuesEqual(ours, base) {
 		return base, nil
 	}
-	if theirs == base {
+	if valuesEqual(theirs, base) {
 		return ours, nil
 	}
-	if ours == base {
+	if valuesEqual(ours, base) {
 		return theirs, nil
 	}
-	return r.conflict(ctx, base, ours, theirs)
+	if valuesEqual(ours, theirs) {
+		return ours, nil
+	}
+	// embedded values are merged field by field, other values such as JSON objects are not
+	if field != nil && field.Type.Elem == nil && schema_gen.IsEmbedded(schema.Types[field.Type.NamedType]) {
+		ourMap, ourOk := ours.(map[string]any)
+		theirMap, theirOk := theirs.(map[string]any)
+		baseMap, baseOk := base.(map[string]any)
+		if ourOk && theirOk && (baseOk || base == nil) {
+			return m.mergeMaps(ctx, schema, schema.Types[field.Type.NamedType], baseMap, ourMap, theirMap)
+		}
+	}
+	return m.resolve(ctx, base, ours, theirs)
+}
+
+// MergeBase returns the best common ancestor of the commits with the given hashes.
+//
+// Nil is returned if the commits do not share any history.
+func (r *Repository) MergeBase(ctx context.Context, a, b object.Hash) (object.Hash, error) {
+	bases, err := r.mergeBase(ctx, a, b)
+	if err != nil || len(bases) == 0 {
+		return nil, err
+	}
+	return bases[0], nil
+}
+
+// IsAncestor returns true if the commit with hash a is an ancestor of or equal to the commit with hash b.
+func (r *Repository) IsAncestor(ctx context.Context, a, b object.Hash) (bool, error) {
+	iter := r.CommitIterator(b)
+	for !iter.Done() {
+		hash, _, err := iter.Next(ctx)
+		if err != nil {
+			return false, err
+		}
+		if hash.Equal(a) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // mergeBase returns the best common ancestor for merging the two given commits.
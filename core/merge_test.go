@@ -172,6 +172,73 @@ func TestMergeConflict(t *testing.T) {
 	assert.Equal(t, "Chad", doc["name"])
 }
 
+func TestMergeCreateConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String, age: Int }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	_, err = txA.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Bob"})
+	require.NoError(t, err)
+
+	_, err = txB.CreateDocument(ctx, "User", map[string]any{"id": "bob", "age": 30})
+	require.NoError(t, err)
+
+	_, err = repo.CommitTransaction(ctx, txA, CommitInfo{})
+	require.NoError(t, err)
+
+	// documents created on both sides are not merged field by field
+	var conflict *MergeConflictError
+	_, err = repo.CommitTransaction(ctx, txB, CommitInfo{})
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "User", conflict.Collection)
+	assert.Equal(t, "bob", conflict.ID)
+	assert.Empty(t, conflict.Path)
+}
+
+func TestMergeCreateHashID(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User @id(strategy: HASH, fields: ["email"]) { email: String, name: String, age: Int }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := txA.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com", "name": "Bob"})
+	require.NoError(t, err)
+
+	_, err = txB.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com", "age": 30})
+	require.NoError(t, err)
+
+	_, err = repo.CommitTransaction(ctx, txA, CommitInfo{})
+	require.NoError(t, err)
+
+	// documents with the same hash id have the same key fields and are merged field by field
+	_, err = repo.CommitTransaction(ctx, txB, CommitInfo{})
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"email": "bob@example.com", "name": "Bob", "age": int64(30)}, doc)
+}
+
 func TestIndependentsSimple(t *testing.T) {
 	ctx := context.Background()
 	schema := `type User { name: String }`
@@ -201,3 +268,87 @@ func TestIndependentsSimple(t *testing.T) {
 	require.Len(t, results, 1)
 	assert.Equal(t, results[0], head)
 }
+
+func TestMergeUniqueConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { email: String @unique }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	_, err = txA.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.NoError(t, err)
+
+	_, err = txB.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.NoError(t, err)
+
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hashA)
+	require.NoError(t, err)
+
+	head := repo.Head()
+
+	var conflict *UniqueConflictError
+	err = repo.Merge(ctx, hashB)
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "User", conflict.Collection)
+	assert.Equal(t, []string{"email"}, conflict.Fields)
+	assert.Equal(t, head, repo.Head())
+}
+
+func TestMergeBaseAndIsAncestor(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String }`)
+	require.NoError(t, err)
+	base := repo.Head()
+
+	tx, err := repo.Transaction(ctx, base)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	ours, err := tx.CommitWithInfo(ctx, CommitInfo{Author: "bob", Message: "add bob"})
+	require.NoError(t, err)
+
+	tx, err = repo.Transaction(ctx, base)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+
+	theirs, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	commit, err := repo.Commit(ctx, ours)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", commit.Author)
+	assert.Equal(t, "add bob", commit.Message)
+	assert.False(t, commit.Time.IsZero())
+
+	mergeBase, err := repo.MergeBase(ctx, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, base, mergeBase)
+
+	isAncestor, err := repo.IsAncestor(ctx, base, ours)
+	require.NoError(t, err)
+	assert.True(t, isAncestor)
+
+	isAncestor, err = repo.IsAncestor(ctx, theirs, ours)
+	require.NoError(t, err)
+	assert.False(t, isAncestor)
+}
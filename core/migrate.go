@@ -0,0 +1,336 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Migration describes how documents are converted when the repo schema changes.
+//
+// Additive changes such as new collections, optional fields, and fields with
+// generated values are applied automatically. Renamed, removed, and retyped
+// collections and fields must be described explicitly.
+type Migration struct {
+	// Renames maps old collection names to new collection names and old
+	// field names in the form Collection.field to new field names.
+	Renames map[string]string
+	// Drops contains the names of removed collections and removed fields
+	// in the form Collection.field.
+	Drops []string
+	// Transforms maps new collection names to functions that convert
+	// documents after renames and drops are applied.
+	//
+	// A transform is required for collections containing fields that change type.
+	// Removed fields that are not dropped are passed to the transform using their old names.
+	Transforms map[string]DocumentTransform
+}
+
+// DocumentTransform converts a document from the given collection to conform to a new schema.
+type DocumentTransform func(ctx context.Context, id string, doc object.Document) (object.Document, error)
+
+// MigrationError is returned when a schema change is not described by the migration.
+type MigrationError struct {
+	// Changes describes the schema changes that require an explicit migration.
+	Changes []string
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("schema migration required: %s", strings.Join(e.Changes, "; "))
+}
+
+// migrationPlan describes how the collections of an old schema map to a new schema.
+type migrationPlan struct {
+	// collections maps new collection names to old collection names.
+	collections map[string]string
+	// fields maps new collection names to mappings of new field names to old field names.
+	fields map[string]map[string]string
+	// added maps new collection names to fields that do not exist in the old schema.
+	added map[string]ast.FieldList
+	// renames maps old collection names to new collection names.
+	renames map[string]string
+}
+
+// MigrateSchema changes the repo schema and converts the documents of the head commit to conform to it.
+//
+// The migration is stored as a new commit so that previous commits remain
+// readable using the schema they were created with.
+func (r *Repository) MigrateSchema(ctx context.Context, input string, migration *Migration) error {
+	if migration == nil {
+		migration = &Migration{}
+	}
+	schema, err := schema_gen.Execute(input)
+	if err != nil {
+		return err
+	}
+	r.update.Lock()
+	defer r.update.Unlock()
+	head := r.Head()
+	tx, err := r.transaction(ctx, head)
+	if err != nil {
+		return err
+	}
+	oldInput, err := r.inputSchema(ctx, tx.data)
+	if err != nil {
+		return err
+	}
+	newInput, err := schema_gen.LoadInput(input)
+	if err != nil {
+		return err
+	}
+	plan, err := diffSchemas(oldInput, newInput, migration)
+	if err != nil {
+		return err
+	}
+	schemaHash, err := EncodeObject(ctx, r.storage, input)
+	if err != nil {
+		return err
+	}
+	next := &Transaction{
+		repo:   r,
+		schema: schema,
+		data:   &object.DataRoot{Collections: make(map[string]object.Hash), Schema: schemaHash},
+		hash:   head,
+	}
+	for _, def := range newInput.Types {
+		if !schema_gen.IsCollection(def) {
+			continue
+		}
+		col, err := next.migrateCollection(ctx, tx, def, plan, migration.Transforms[def.Name])
+		if err != nil {
+			return err
+		}
+		next.data.Collections[def.Name], err = next.storeObject(ctx, col)
+		if err != nil {
+			return err
+		}
+	}
+	err = next.validateCollections(ctx)
+	if err != nil {
+		return err
+	}
+	hash, err := next.commit(ctx, CommitInfo{Message: "Migrate schema"})
+	if err != nil {
+		return err
+	}
+	return r.setHead(ctx, hash)
+}
+
+// migrateCollection returns a collection containing the converted documents of the old collection mapped to the definition.
+func (t *Transaction) migrateCollection(ctx context.Context, prev *Transaction, def *ast.Definition, plan *migrationPlan, transform DocumentTransform) (*object.Collection, error) {
+	col := &object.Collection{Documents: make(map[string]object.Hash)}
+	old, ok := plan.collections[def.Name]
+	if !ok {
+		return col, nil // new collections are empty
+	}
+	iter, err := prev.DocumentIterator(ctx, old)
+	if err != nil {
+		return nil, err
+	}
+	for !iter.Done() {
+		id, _, doc, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out := object.NewDocument()
+		for field, from := range plan.fields[def.Name] {
+			value, ok := doc[from]
+			if !ok {
+				continue
+			}
+			if fd := def.Fields.ForName(field); fd != nil && schema_gen.IsAbstract(t.schema.Types[fd.Type.Name()]) {
+				value = renameReferences(value, plan.renames)
+			}
+			out[field] = value
+		}
+		if transform != nil {
+			out, err = transform(ctx, id, out)
+			if err != nil {
+				return nil, fmt.Errorf("failed to migrate document %s in %s: %w", id, def.Name, err)
+			}
+		}
+		err = t.createGenerated(ctx, &ast.Definition{Name: def.Name, Fields: plan.added[def.Name]}, out)
+		if err != nil {
+			return nil, err
+		}
+		col.Documents[id], err = t.storeObject(ctx, out)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return col, nil
+}
+
+// validateCollections ensures all documents of the transaction conform to its schema.
+func (t *Transaction) validateCollections(ctx context.Context) error {
+	for collection := range t.data.Collections {
+		def := t.schema.Types[collection]
+		iter, err := t.DocumentIterator(ctx, collection)
+		if err != nil {
+			return err
+		}
+		for !iter.Done() {
+			id, _, doc, err := iter.Next(ctx)
+			if err != nil {
+				return err
+			}
+			err = t.validateDocument(ctx, def, doc)
+			if err != nil {
+				return fmt.Errorf("invalid document %s in %s: %w", id, collection, err)
+			}
+		}
+	}
+	_, err := t.uniqueIndex(ctx)
+	return err
+}
+
+// diffSchemas returns a plan for converting documents from the old input schema to the new input schema.
+//
+// A MigrationError is returned if any changes are not described by the migration.
+func diffSchemas(old, schema *ast.Schema, migration *Migration) (*migrationPlan, error) {
+	plan := &migrationPlan{
+		collections: make(map[string]string),
+		fields:      make(map[string]map[string]string),
+		added:       make(map[string]ast.FieldList),
+		renames:     make(map[string]string),
+	}
+	for from, to := range migration.Renames {
+		collection, field, isField := strings.Cut(from, ".")
+		def := old.Types[collection]
+		if !schema_gen.IsCollection(def) || (isField && def.Fields.ForName(field) == nil) {
+			return nil, fmt.Errorf("invalid rename of %s", from)
+		}
+		if !isField {
+			plan.renames[from] = to
+		}
+	}
+	for _, name := range migration.Drops {
+		collection, field, isField := strings.Cut(name, ".")
+		def := old.Types[collection]
+		if !schema_gen.IsCollection(def) || (isField && def.Fields.ForName(field) == nil) {
+			return nil, fmt.Errorf("invalid drop of %s", name)
+		}
+	}
+	var changes []string
+	for _, def := range old.Types {
+		if !schema_gen.IsCollection(def) || slices.Contains(migration.Drops, def.Name) {
+			continue
+		}
+		name := def.Name
+		if to, ok := plan.renames[name]; ok {
+			name = to
+		}
+		if !schema_gen.IsCollection(schema.Types[name]) {
+			changes = append(changes, fmt.Sprintf("collection %s was removed", def.Name))
+			continue
+		}
+		plan.collections[name] = def.Name
+	}
+	for _, def := range schema.Types {
+		from, ok := plan.collections[def.Name]
+		if !ok {
+			continue
+		}
+		changes = append(changes, plan.diffFields(old.Types[from], def, migration)...)
+	}
+	slices.Sort(changes)
+	if len(changes) > 0 {
+		return nil, &MigrationError{Changes: changes}
+	}
+	return plan, nil
+}
+
+// diffFields adds the field mappings between the old and new definitions to the plan.
+//
+// The returned changes describe fields that require an explicit migration.
+func (p *migrationPlan) diffFields(old, def *ast.Definition, migration *Migration) []string {
+	_, transformed := migration.Transforms[def.Name]
+	fields := make(map[string]string)
+	var changes []string
+	for _, field := range old.Fields {
+		key := old.Name + "." + field.Name
+		if !isStored(field) || slices.Contains(migration.Drops, key) {
+			continue
+		}
+		name := field.Name
+		if to, ok := migration.Renames[key]; ok {
+			name = to
+		}
+		target := def.Fields.ForName(name)
+		if target == nil || !isStored(target) {
+			if transformed {
+				fields[field.Name] = field.Name // converted by the transform
+			} else {
+				changes = append(changes, fmt.Sprintf("field %s was removed", key))
+			}
+			continue
+		}
+		fields[name] = field.Name
+		if !transformed && !p.compatibleType(field.Type, target.Type) {
+			changes = append(changes, fmt.Sprintf("field %s changed type from %s to %s", key, field.Type, target.Type))
+		}
+	}
+	for _, field := range def.Fields {
+		if _, ok := fields[field.Name]; ok || !isStored(field) {
+			continue
+		}
+		if field.Type.NonNull && !schema_gen.IsGenerated(field) && !transformed {
+			changes = append(changes, fmt.Sprintf("field %s.%s is required", def.Name, field.Name))
+		}
+		p.added[def.Name] = append(p.added[def.Name], field)
+	}
+	p.fields[def.Name] = fields
+	return changes
+}
+
+// compatibleType returns true if values of the old type are valid values of the new type.
+func (p *migrationPlan) compatibleType(old, typ *ast.Type) bool {
+	if typ.NonNull && !old.NonNull {
+		return false
+	}
+	if (old.Elem == nil) != (typ.Elem == nil) {
+		return false
+	}
+	if old.Elem != nil {
+		return p.compatibleType(old.Elem, typ.Elem)
+	}
+	name := old.NamedType
+	if to, ok := p.renames[name]; ok {
+		name = to
+	}
+	return name == typ.NamedType
+}
+
+// isStored returns true if the field value is stored in documents.
+func isStored(field *ast.FieldDefinition) bool {
+	if schema_gen.IsSystemField(field.Name) {
+		return false
+	}
+	_, ok := schema_gen.InverseOf(field)
+	return !ok
+}
+
+// renameReferences returns the typed reference value with renamed collections replaced.
+func renameReferences(value any, renames map[string]string) any {
+	if list, ok := value.([]any); ok {
+		out := make([]any, len(list))
+		for i, v := range list {
+			out[i] = renameReferences(v, renames)
+		}
+		return out
+	}
+	collection, id, ok := ParseReference(value)
+	if !ok {
+		return value
+	}
+	if to, ok := renames[collection]; ok {
+		return FormatReference(to, id)
+	}
+	return value
+}
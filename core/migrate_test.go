@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rodent-software/capy/object"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSchemaAdditive(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String }`)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hash)
+	require.NoError(t, err)
+
+	err = repo.MigrateSchema(ctx, `type User { name: String role: String! @default(value: "member") } type Post { title: String }`, nil)
+	require.NoError(t, err)
+	require.NotNil(t, repo.Schema().Types["Post"])
+
+	tx, err = repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Bob", "role": "member"}, doc)
+
+	// previous revisions keep their original schema
+	old, err := repo.Transaction(ctx, hash)
+	require.NoError(t, err)
+	assert.Nil(t, old.Schema().Types["Post"])
+
+	doc, err = old.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Bob"}, doc)
+
+	reopened, err := OpenRepository(ctx, storage)
+	require.NoError(t, err)
+	assert.NotNil(t, reopened.Schema().Types["Post"])
+}
+
+func TestMigrateSchemaRequiresMigration(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String age: String }`)
+	require.NoError(t, err)
+
+	var migrationErr *MigrationError
+	err = repo.MigrateSchema(ctx, `type User { fullName: String age: Int }`, nil)
+	require.ErrorAs(t, err, &migrationErr)
+	assert.Equal(t, []string{
+		"field User.age changed type from String to Int",
+		"field User.name was removed",
+	}, migrationErr.Changes)
+
+	err = repo.MigrateSchema(ctx, `type Person { name: String }`, nil)
+	require.ErrorAs(t, err, &migrationErr)
+
+	err = repo.MigrateSchema(ctx, `type User { name: String age: String email: String! }`, nil)
+	require.ErrorAs(t, err, &migrationErr)
+}
+
+func TestMigrateSchemaRenameAndTransform(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String age: String nick: String }`)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob", "age": "42", "nick": "bobby"})
+	require.NoError(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hash)
+	require.NoError(t, err)
+
+	err = repo.MigrateSchema(ctx, `type Person { fullName: String age: Int }`, &Migration{
+		Renames: map[string]string{"User": "Person", "User.name": "fullName"},
+		Drops:   []string{"User.nick"},
+		Transforms: map[string]DocumentTransform{
+			"Person": func(ctx context.Context, id string, doc object.Document) (object.Document, error) {
+				var age int64
+				_, err := fmt.Sscan(doc["age"].(string), &age)
+				doc["age"] = age
+				return doc, err
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	tx, err = repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "Person", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"fullName": "Bob", "age": int64(42)}, doc)
+}
+
+func TestMigrateSchemaInvalidDocuments(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String }`)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hash)
+	require.NoError(t, err)
+
+	err = repo.MigrateSchema(ctx, `type User { name: String @unique }`, nil)
+	var conflict *UniqueConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, hash, repo.Head())
+}
+
+func TestMergeMigratedSchema(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, `type User { name: String }`)
+	require.NoError(t, err)
+	base := repo.Head()
+
+	tx, err := repo.Transaction(ctx, base)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	branch, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.MigrateSchema(ctx, `type User { name: String email: String }`, nil)
+	require.NoError(t, err)
+	migrated := repo.Head()
+
+	err = repo.Merge(ctx, branch)
+	require.NoError(t, err)
+	assert.NotNil(t, repo.Schema().Types["User"].Fields.ForName("email"))
+
+	err = repo.setHead(ctx, migrated)
+	require.NoError(t, err)
+
+	err = repo.MigrateSchema(ctx, `type User { fullName: String email: String }`, &Migration{
+		Renames: map[string]string{"User.name": "fullName"},
+	})
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, branch)
+	require.ErrorContains(t, err, "merge conflict")
+}
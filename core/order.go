@@ -0,0 +1,111 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// ascendingOrder orders values from smallest to largest.
+	ascendingOrder = "ASC"
+	// descendingOrder orders values from largest to smallest.
+	descendingOrder = "DESC"
+)
+
+// CompareDocuments compares two documents from the given collection using the order input.
+//
+// The order is a list of inputs that each map a field name to an order direction.
+// Documents with equal field values are ordered by id.
+func (t *Transaction) CompareDocuments(collection string, aID string, a map[string]any, bID string, b map[string]any, order any) (int, error) {
+	def, ok := t.schema.Types[collection]
+	if !ok {
+		return 0, fmt.Errorf("collection does not exist: %s", collection)
+	}
+	inputs, _ := order.([]any)
+	for _, input := range inputs {
+		fields, _ := input.(map[string]any)
+		for _, field := range orderFields(def, fields) {
+			var res int
+			if field == "id" {
+				res = cmp.Compare(aID, bID)
+			} else {
+				fd := def.Fields.ForName(field)
+				res = compareOrder(t.schema.Types[fd.Type.Name()], a[field], b[field])
+			}
+			if fields[field] == descendingOrder {
+				res = -res
+			}
+			if res != 0 {
+				return res, nil
+			}
+		}
+	}
+	return cmp.Compare(aID, bID), nil
+}
+
+// orderFields returns the fields of the order input in definition order.
+func orderFields(def *ast.Definition, input map[string]any) []string {
+	fields := make([]string, 0, len(input))
+	if _, ok := input["id"]; ok {
+		fields = append(fields, "id")
+	}
+	for _, field := range def.Fields {
+		if _, ok := input[field.Name]; ok {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// compareOrder compares two field values for ordering.
+//
+// Missing values are ordered before all other values.
+func compareOrder(def *ast.Definition, a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if av, ok := a.(bool); ok {
+		bv, _ := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case av:
+			return 1
+		default:
+			return -1
+		}
+	}
+	res, err := compareValue(def, a, b)
+	if err != nil {
+		return 0
+	}
+	return res
+}
+
+// compareValue compares the value to the filter value using the ordering of the given definition.
+//
+// Enum values are ordered by their declaration order.
+func compareValue(def *ast.Definition, value any, filter any) (int, error) {
+	if def != nil && def.Kind == ast.Enum {
+		return cmp.Compare(enumIndex(def, value), enumIndex(def, filter)), nil
+	}
+	return filterCompare(value, filter)
+}
+
+// enumIndex returns the declaration index of the enum value or -1 if it is not valid.
+func enumIndex(def *ast.Definition, value any) int {
+	name, _ := value.(string)
+	for i, v := range def.EnumValues {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareDocuments(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	enum Size { SMALL, LARGE }
+	type Box {
+		size: Size
+		weight: Float
+		open: Boolean
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	small := map[string]any{"size": "SMALL", "weight": 2.0, "open": true}
+	large := map[string]any{"size": "LARGE", "weight": 1.0}
+
+	order := []any{map[string]any{"size": "ASC"}}
+	res, err := tx.CompareDocuments("Box", "a", small, "b", large, order)
+	require.NoError(t, err)
+	assert.Equal(t, -1, res)
+
+	order = []any{map[string]any{"weight": "DESC"}}
+	res, err = tx.CompareDocuments("Box", "a", small, "b", large, order)
+	require.NoError(t, err)
+	assert.Equal(t, -1, res)
+
+	order = []any{map[string]any{"open": "ASC"}}
+	res, err = tx.CompareDocuments("Box", "a", small, "b", large, order)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res)
+
+	res, err = tx.CompareDocuments("Box", "a", small, "b", small, nil)
+	require.NoError(t, err)
+	assert.Equal(t, -1, res)
+}
+
+func TestValidateEnumValue(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	enum Size { SMALL, LARGE }
+	type Box { size: Size }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Box", map[string]any{"size": "SMALL"})
+	require.NoError(t, err)
+
+	var verr *ValidationError
+	_, err = tx.CreateDocument(ctx, "Box", map[string]any{"size": "MEDIUM"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, TypeErrorCode, verr.Code)
+}
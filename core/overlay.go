@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/rodent-software/capy/codec"
+	"github.com/rodent-software/capy/object"
+)
+
+// objectStore loads and stores encoded objects.
+type objectStore interface {
+	// loadObject returns the encoded object with the given hash.
+	loadObject(ctx context.Context, hash object.Hash) ([]byte, error)
+	// storeObject encodes the value and returns its hash.
+	storeObject(ctx context.Context, value any) (object.Hash, error)
+	// objectCache returns the cache of decoded objects.
+	objectCache() *objectCache
+}
+
+// overlayStorage is a Storage that buffers the objects written by a transaction in memory.
+type overlayStorage map[string][]byte
+
+func (o overlayStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := o[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (o overlayStorage) Put(ctx context.Context, key string, value []byte) error {
+	o[key] = value
+	return nil
+}
+
+func (r *Repository) storeObject(ctx context.Context, value any) (object.Hash, error) {
+	return EncodeObject(ctx, r.storage, value)
+}
+
+// loadObject returns the encoded object with the given hash.
+//
+// Objects written by the transaction are read from its overlay before the repo storage.
+func (t *Transaction) loadObject(ctx context.Context, hash object.Hash) ([]byte, error) {
+	if data, ok := t.objects[hash.String()]; ok {
+		return data, nil
+	}
+	return t.repo.loadObject(ctx, hash)
+}
+
+// storeObject encodes the value into the transaction overlay and returns its hash.
+//
+// Objects do not reach the repo storage until the transaction is committed.
+func (t *Transaction) storeObject(ctx context.Context, value any) (object.Hash, error) {
+	if t.objects == nil {
+		t.objects = make(overlayStorage)
+	}
+	return EncodeObject(ctx, t.objects, value)
+}
+
+// collection returns the collection with the given name.
+//
+// Collections modified by the transaction are returned from memory and must not be modified by the caller.
+func (t *Transaction) collection(ctx context.Context, name string) (*object.Collection, error) {
+	if col, ok := t.collections[name]; ok {
+		return col, nil
+	}
+	hash, ok := t.data.Collections[name]
+	if !ok {
+		return nil, fmt.Errorf("collection does not exist: %s", name)
+	}
+	return loadCollection(ctx, t, hash)
+}
+
+// modifyCollection returns the collection with the given name and keeps it in memory until it is encoded.
+//
+// Encoding is deferred so that bulk writes do not re-encode the entire collection for each document.
+func (t *Transaction) modifyCollection(ctx context.Context, name string) (*object.Collection, error) {
+	col, err := t.collection(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.collections[name]; !ok {
+		// loaded collections are shared through the object cache
+		col = &object.Collection{Documents: maps.Clone(col.Documents)}
+	}
+	if t.collections == nil {
+		t.collections = make(map[string]*object.Collection)
+	}
+	t.collections[name] = col
+	return col, nil
+}
+
+// encodeCollections encodes the modified collections into the overlay and updates the data root to reference them.
+func (t *Transaction) encodeCollections(ctx context.Context) error {
+	for name, col := range t.collections {
+		hash, err := t.storeObject(ctx, col)
+		if err != nil {
+			return err
+		}
+		t.data.Collections[name] = hash
+	}
+	t.collections = nil
+	return nil
+}
+
+// flush writes the buffered objects reachable from the transaction data root to the repo storage.
+//
+// Buffered objects that are no longer reachable, such as those discarded by
+// rolling back to a savepoint, are kept in the overlay.
+func (t *Transaction) flush(ctx context.Context) error {
+	if len(t.objects) == 0 {
+		return nil
+	}
+	keys := make([]string, 0)
+	if t.data.Schema != nil {
+		keys = append(keys, t.data.Schema.String())
+	}
+	for _, colHash := range t.data.Collections {
+		data, ok := t.objects[colHash.String()]
+		if !ok {
+			continue
+		}
+		keys = append(keys, colHash.String())
+		col, err := codec.NewDecoder(bytes.NewBuffer(data)).DecodeCollection()
+		if err != nil {
+			return err
+		}
+		for _, docHash := range col.Documents {
+			keys = append(keys, docHash.String())
+		}
+	}
+	for _, key := range keys {
+		data, ok := t.objects[key]
+		if !ok {
+			continue
+		}
+		err := t.repo.storage.Put(ctx, key, data)
+		if err != nil {
+			return err
+		}
+		delete(t.objects, key)
+	}
+	return nil
+}
+
+// loadDataRoot returns the data root with the given hash from the store.
+//
+// The data root is shared and must not be modified.
+func loadDataRoot(ctx context.Context, objects objectStore, hash object.Hash) (*object.DataRoot, error) {
+	return decodeObject(ctx, objects.objectCache(), hash, objects.loadObject, (*codec.Decoder).DecodeDataRoot)
+}
+
+// loadCollection returns the collection with the given hash from the store.
+//
+// The collection is shared and must not be modified.
+func loadCollection(ctx context.Context, objects objectStore, hash object.Hash) (*object.Collection, error) {
+	return decodeObject(ctx, objects.objectCache(), hash, objects.loadObject, (*codec.Decoder).DecodeCollection)
+}
+
+// loadDocument returns a copy of the document with the given hash from the store.
+func loadDocument(ctx context.Context, objects objectStore, hash object.Hash) (object.Document, error) {
+	doc, err := decodeObject(ctx, objects.objectCache(), hash, objects.loadObject, (*codec.Decoder).DecodeDocument)
+	if err != nil {
+		return nil, err
+	}
+	// documents are returned to callers that may modify them
+	return copyValue(doc).(object.Document), nil
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// cascadeAction deletes documents referencing a deleted document.
+	cascadeAction = "CASCADE"
+	// setNullAction removes references to a deleted document.
+	setNullAction = "SET_NULL"
+	// restrictAction prevents deleting referenced documents.
+	restrictAction = "RESTRICT"
+)
+
+// reference is a relation field of a document that references another document.
+type reference struct {
+	collection string
+	id         string
+	field      *ast.FieldDefinition
+}
+
+// is returns true if the reference is contained in the document with the given collection and id.
+func (r reference) is(collection, id string) bool {
+	return r.collection == collection && r.id == id
+}
+
+// deleteAction returns the action to take on the relation field when a referenced document is deleted.
+func deleteAction(field *ast.FieldDefinition) string {
+	if dir := field.Directives.ForName("relation"); dir != nil {
+		action, _ := dir.ArgumentMap(nil)["onDelete"].(string)
+		return action
+	}
+	if field.Type.Elem == nil && field.Type.NonNull {
+		return restrictAction
+	}
+	return setNullAction
+}
+
+// isRelation returns true if the field stores references to documents in another collection.
+func (t *Transaction) isRelation(field *ast.FieldDefinition) bool {
+	if _, ok := schema_gen.InverseOf(field); ok {
+		return false
+	}
+	if schema_gen.IsAbstract(t.schema.Types[field.Type.Name()]) {
+		return true
+	}
+	_, ok := t.data.Collections[field.Type.Name()]
+	return ok
+}
+
+// references returns all relation fields that reference the document in the given collection with the matching id.
+func (t *Transaction) references(ctx context.Context, collection, id string) ([]reference, error) {
+	index, err := t.referenceIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(index.targets[documentKey{collection, id}]), nil
+}
+
+// inverseValue returns the value of the inverse field for the document in the given collection with the matching id.
+func (t *Transaction) inverseValue(ctx context.Context, collection, id string, field *ast.FieldDefinition) (any, error) {
+	of, _ := schema_gen.InverseOf(field)
+	refs, err := t.references(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]any, 0)
+	for _, ref := range refs {
+		if ref.collection == field.Type.Name() && ref.field.Name == of && !slices.Contains(ids, any(ref.id)) {
+			ids = append(ids, ref.id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b any) int { return strings.Compare(a.(string), b.(string)) })
+	if field.Type.Elem != nil {
+		return ids, nil
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids[0], nil
+}
+
+// InverseRelation returns the value of the inverse relation field for the document in the given collection with the matching id.
+//
+// The result contains the ids of the documents that reference the document through the relation the field is the inverse of.
+func (t *Transaction) InverseRelation(ctx context.Context, collection, id, field string) (any, error) {
+	def, ok := t.schema.Types[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection does not exist: %s", collection)
+	}
+	fd := def.Fields.ForName(field)
+	if fd == nil {
+		return nil, fmt.Errorf("invalid document field %s", field)
+	}
+	if _, ok := schema_gen.InverseOf(fd); !ok {
+		return nil, fmt.Errorf("field is not an inverse relation %s", field)
+	}
+	return t.inverseValue(ctx, collection, id, fd)
+}
+
+// deleteReference applies the delete action of the reference to the document containing it.
+func (t *Transaction) deleteReference(ctx context.Context, ref reference, collection, id string) error {
+	exists, err := t.documentExists(ctx, ref.collection, ref.id)
+	if err != nil || !exists {
+		return err // already deleted by a previous action
+	}
+	if deleteAction(ref.field) == cascadeAction {
+		return t.deleteDocument(ctx, ref.collection, ref.id)
+	}
+	doc, err := t.ReadDocument(ctx, ref.collection, ref.id)
+	if err != nil {
+		return err
+	}
+	target := any(id)
+	if schema_gen.IsAbstract(t.schema.Types[ref.field.Type.Name()]) {
+		target = FormatReference(collection, id)
+	}
+	switch v := doc[ref.field.Name].(type) {
+	case []any:
+		doc[ref.field.Name] = slices.DeleteFunc(slices.Clone(v), func(e any) bool { return e == target })
+	default:
+		delete(doc, ref.field.Name)
+	}
+	err = t.validateReferencing(ctx, ref.collection, doc)
+	if err != nil {
+		return err
+	}
+	return t.writeDocument(ctx, ref.collection, ref.id, doc)
+}
+
+// validateReferencing ensures a document with a removed reference still conforms to its definition.
+//
+// Relation errors are ignored because other references to deleted documents are removed by their own actions.
+func (t *Transaction) validateReferencing(ctx context.Context, collection string, doc map[string]any) error {
+	errs, _ := t.validateDocument(ctx, t.schema.Types[collection], doc).(ValidationErrors)
+	errs = slices.DeleteFunc(errs, func(e *ValidationError) bool {
+		return e.Code == RelationErrorCode
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteDocumentSetNull(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String } type Post { author: User, editors: [User] }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Bob"})
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "alice", "name": "Alice"})
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Post", map[string]any{
+		"author":  map[string]any{"id": "bob"},
+		"editors": []any{map[string]any{"id": "bob"}, map[string]any{"id": "alice"}},
+	})
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "User", "bob")
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "Post", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"editors": []any{"alice"}}, doc)
+}
+
+func TestDeleteDocumentCascade(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String } type Post { author: User @relation(onDelete: CASCADE) }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Bob"})
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Post", map[string]any{"author": map[string]any{"id": "bob"}})
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "User", "bob")
+	require.NoError(t, err)
+
+	exists, err := tx.documentExists(ctx, "Post", id)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDeleteDocumentRestrict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String } type Post { author: User! }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Bob"})
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"author": map[string]any{"id": "bob"}})
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "User", "bob")
+	require.Error(t, err)
+
+	exists, err := tx.documentExists(ctx, "User", "bob")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCreateDocumentMissingRelation(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String } type Post { author: User }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"author": map[string]any{"id": "bob"}})
+	require.Error(t, err)
+}
+
+func TestInverseRelation(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { posts: [Post] @inverse(of: "author") } type Post { author: User }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob"})
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"id": "1", "author": map[string]any{"id": "bob"}})
+	require.NoError(t, err)
+
+	posts, err := tx.InverseRelation(ctx, "User", "bob", "posts")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"1"}, posts)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"id": "2", "author": map[string]any{"id": "bob"}})
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "Post", "1", map[string]any{"author": map[string]any{"disconnect": true}})
+	require.NoError(t, err)
+
+	posts, err = tx.InverseRelation(ctx, "User", "bob", "posts")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"2"}, posts)
+
+	err = tx.PatchDocument(ctx, "User", "bob", map[string]any{"posts": map[string]any{"set": []any{"1"}}})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, TypeErrorCode, validationErr.Code)
+
+	doc, err := tx.ReadDocument(ctx, "User", "bob")
+	require.NoError(t, err)
+	assert.NotContains(t, doc, "posts")
+}
+
+func TestPatchRelationListInvalidArgument(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Tag { name: String } type Post { tags: [Tag] }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "Post", map[string]any{"tags": []any{map[string]any{"name": "go"}}})
+	require.NoError(t, err)
+
+	for _, patch := range []map[string]any{
+		{"tags": map[string]any{"create": "go"}},
+		{"tags": map[string]any{"create": []any{"go"}}},
+		{"tags": map[string]any{"update": "go"}},
+	} {
+		err = tx.PatchDocument(ctx, "Post", id, patch)
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, TypeErrorCode, validationErr.Code)
+	}
+}
+
+func TestDeleteDocumentNotFound(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "User", "bob")
+	require.Error(t, err)
+}
+
+func TestDeleteDocumentValidatesReferencing(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Tag { name: String } type Post { tags: [Tag] @length(min: 1) }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Tag", map[string]any{"id": "go", "name": "Go"})
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "Post", map[string]any{"tags": []any{map[string]any{"id": "go"}}})
+	require.NoError(t, err)
+
+	err = tx.DeleteDocument(ctx, "Tag", "go")
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, LengthErrorCode, errs[0].Code)
+
+	exists, err := tx.documentExists(ctx, "Tag", "go")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -4,28 +4,52 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/rodent-software/capy/codec"
 	"github.com/rodent-software/capy/graphql/schema_gen"
 	"github.com/rodent-software/capy/object"
 
-	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
 const (
-	//SchemaKey is the key used to store the input schema.
+	// SchemaKey is the key used to store the input schema of repos created before schemas were versioned.
+	//
+	// Input schemas are now stored as objects referenced by each data root.
 	SchemaKey = "schema"
 	// HeadKey is the key used to store the repo head.
 	HeadKey = "head"
+	// ShallowKey is the key used to store the shallow commits of repos cloned with a limited depth.
+	ShallowKey = "shallow"
 )
 
 // Repository contains all database objects.
+//
+// Repositories are safe for concurrent use. Operations that move the head are
+// serialized, while transactions read a snapshot of the commit they are based on.
 type Repository struct {
+	// mu protects the head, schema, cached schemas, and transaction roots.
+	mu sync.RWMutex
+	// update serializes operations that move the head.
+	update   sync.Mutex
 	head     object.Hash
 	schema   *ast.Schema
+	schemas  map[string]*ast.Schema
 	storage  Storage
 	conflict MergeConflictResolver
+	clock    func() time.Time
+	times    *timeIndex
+	// shallow contains the commits whose parents are not stored in the repo.
+	shallow map[string]struct{}
+	fetcher Fetcher
+	// cache contains decoded objects shared by all transactions.
+	cache *objectCache
+	// indexes contains the indexes of recent data roots shared by all transactions.
+	indexes *objectCache
+	// roots counts the open transactions retaining each commit.
+	roots map[string]int
 }
 
 func NewRepository(head object.Hash, schemaInput string, storage Storage) (*Repository, error) {
@@ -36,14 +60,18 @@ func NewRepository(head object.Hash, schemaInput string, storage Storage) (*Repo
 	return &Repository{
 		head:     head,
 		schema:   schema,
+		schemas:  make(map[string]*ast.Schema),
 		storage:  storage,
 		conflict: TheirsConflictResolver,
+		clock:    time.Now,
+		cache:    newObjectCache(DefaultCacheSize),
+		indexes:  newObjectCache(indexCacheSize),
 	}, nil
 }
 
 // InitRepository initializes a repo using the given schema and storage backend.
 func InitRepository(ctx context.Context, storage Storage, schemaInput string) (*Repository, error) {
-	schema, err := gqlparser.LoadSchema(&ast.Source{Input: schemaInput})
+	schema, err := schema_gen.LoadInput(schemaInput)
 	if err != nil {
 		return nil, err
 	}
@@ -57,12 +85,18 @@ func InitRepository(ctx context.Context, storage Storage, schemaInput string) (*
 		return nil, err
 	}
 
+	schemaHash, err := EncodeObject(ctx, storage, schemaInput)
+	if err != nil {
+		return nil, err
+	}
+
 	// create initial data root
 	data := &object.DataRoot{
 		Collections: make(map[string]object.Hash),
+		Schema:      schemaHash,
 	}
 	for _, t := range schema.Types {
-		if !t.BuiltIn && t.Kind == ast.Object {
+		if schema_gen.IsCollection(t) {
 			data.Collections[t.Name] = collectionHash
 		}
 	}
@@ -74,6 +108,7 @@ func InitRepository(ctx context.Context, storage Storage, schemaInput string) (*
 	// create initial commit
 	commit := &object.Commit{
 		DataRoot: dataHash,
+		Time:     time.Now().UTC(),
 	}
 	commitHash, err := EncodeObject(ctx, storage, commit)
 	if err != nil {
@@ -84,10 +119,6 @@ func InitRepository(ctx context.Context, storage Storage, schemaInput string) (*
 	if err != nil {
 		return nil, err
 	}
-	err = storage.Put(ctx, SchemaKey, []byte(schemaInput))
-	if err != nil {
-		return nil, err
-	}
 	return NewRepository(commitHash, schemaInput, storage)
 }
 
@@ -97,61 +128,177 @@ func OpenRepository(ctx context.Context, storage Storage) (*Repository, error) {
 	if !errors.Is(err, ErrNotFound) && err != nil {
 		return nil, err
 	}
-	schemaInput, err := storage.Get(ctx, SchemaKey)
+	repo := &Repository{storage: storage}
+	commit, err := repo.Commit(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	dataRoot, err := repo.DataRoot(ctx, commit.DataRoot)
 	if err != nil {
 		return nil, err
 	}
-	return NewRepository(head, string(schemaInput), storage)
+	schemaInput, err := repo.SchemaInput(ctx, dataRoot)
+	if err != nil {
+		return nil, err
+	}
+	shallow, err := loadShallow(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	repo, err = NewRepository(head, schemaInput, storage)
+	if err != nil {
+		return nil, err
+	}
+	repo.shallow = shallow
+	return repo, nil
 }
 
 // Schema returns the schema that describes the collections.
 func (r *Repository) Schema() *ast.Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.schema
 }
 
+// CommitSchema returns the schema that describes the collections of the commit with the given hash.
+func (r *Repository) CommitSchema(ctx context.Context, hash object.Hash) (*ast.Schema, error) {
+	commit, err := r.Commit(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	dataRoot, err := r.DataRoot(ctx, commit.DataRoot)
+	if err != nil {
+		return nil, err
+	}
+	return r.dataSchema(ctx, dataRoot)
+}
+
+// SchemaInput returns the input schema describing the collections of the data root.
+func (r *Repository) SchemaInput(ctx context.Context, dataRoot *object.DataRoot) (string, error) {
+	if dataRoot.Schema == nil {
+		// data roots created before schemas were versioned use the repo schema
+		data, err := r.storage.Get(ctx, SchemaKey)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	data, err := r.storage.Get(ctx, dataRoot.Schema.String())
+	if err != nil {
+		return "", err
+	}
+	dec := codec.NewDecoder(bytes.NewBuffer(data))
+	return dec.DecodeString()
+}
+
+// inputSchema returns the input schema describing the collections of the data root.
+func (r *Repository) inputSchema(ctx context.Context, dataRoot *object.DataRoot) (*ast.Schema, error) {
+	input, err := r.SchemaInput(ctx, dataRoot)
+	if err != nil {
+		return nil, err
+	}
+	return schema_gen.LoadInput(input)
+}
+
+// dataSchema returns the schema describing the collections of the data root.
+//
+// Schemas are cached by the hash of their input.
+func (r *Repository) dataSchema(ctx context.Context, dataRoot *object.DataRoot) (*ast.Schema, error) {
+	key := dataRoot.Schema.String()
+	r.mu.RLock()
+	schema, ok := r.schemas[key]
+	r.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+	input, err := r.SchemaInput(ctx, dataRoot)
+	if err != nil {
+		return nil, err
+	}
+	schema, err = schema_gen.Execute(input)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.schemas[key] = schema
+	r.mu.Unlock()
+	return schema, nil
+}
+
+// setHead sets the repo head to the commit with the given hash.
+//
+// The repo schema is updated to the schema of the commit and the head is persisted to storage.
+// Callers must hold the update lock.
+func (r *Repository) setHead(ctx context.Context, hash object.Hash) error {
+	commit, err := r.Commit(ctx, hash)
+	if err != nil {
+		return err
+	}
+	dataRoot, err := r.DataRoot(ctx, commit.DataRoot)
+	if err != nil {
+		return err
+	}
+	schema, err := r.dataSchema(ctx, dataRoot)
+	if err != nil {
+		return err
+	}
+	err = r.storage.Put(ctx, HeadKey, hash)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.head = hash
+	r.schema = schema
+	r.mu.Unlock()
+	return nil
+}
+
 // Head returns the repo head hash.
 func (r *Repository) Head() object.Hash {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.head
 }
 
 // Commit returns the commit with the given hash.
+//
+// Shallow commits are returned without parents. The commit is shared and must not be modified.
 func (r *Repository) Commit(ctx context.Context, hash object.Hash) (*object.Commit, error) {
-	data, err := r.storage.Get(ctx, hash.String())
+	load := func(ctx context.Context, hash object.Hash) ([]byte, error) {
+		return r.storage.Get(ctx, hash.String())
+	}
+	commit, err := decodeObject(ctx, r.cache, hash, load, (*codec.Decoder).DecodeCommit)
 	if err != nil {
 		return nil, err
 	}
-	dec := codec.NewDecoder(bytes.NewBuffer(data))
-	return dec.DecodeCommit()
+	if _, ok := r.shallow[hash.String()]; ok {
+		grafted := *commit
+		grafted.Parents = nil
+		return &grafted, nil
+	}
+	return commit, nil
 }
 
 // DataRoot returns the data root with the given hash.
+//
+// The data root is shared and must not be modified.
 func (r *Repository) DataRoot(ctx context.Context, hash object.Hash) (*object.DataRoot, error) {
-	data, err := r.storage.Get(ctx, hash.String())
-	if err != nil {
-		return nil, err
-	}
-	dec := codec.NewDecoder(bytes.NewBuffer(data))
-	return dec.DecodeDataRoot()
+	return loadDataRoot(ctx, r, hash)
 }
 
 // Collection returns the collection with the given hash.
+//
+// Collections missing from partial repos are retrieved using the fetcher.
+// The collection is shared and must not be modified.
 func (r *Repository) Collection(ctx context.Context, hash object.Hash) (*object.Collection, error) {
-	data, err := r.storage.Get(ctx, hash.String())
-	if err != nil {
-		return nil, err
-	}
-	dec := codec.NewDecoder(bytes.NewBuffer(data))
-	return dec.DecodeCollection()
+	return loadCollection(ctx, r, hash)
 }
 
 // Document returns the document with the given hash.
+//
+// Documents missing from partial repos are retrieved using the fetcher.
 func (r *Repository) Document(ctx context.Context, hash object.Hash) (object.Document, error) {
-	data, err := r.storage.Get(ctx, hash.String())
-	if err != nil {
-		return nil, err
-	}
-	dec := codec.NewDecoder(bytes.NewBuffer(data))
-	return dec.DecodeDocument()
+	return loadDocument(ctx, r, hash)
 }
 
 // Dump returns a mapping of all collections and document ids in the repo.
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,3 +20,73 @@ func TestRepositoryInit(t *testing.T) {
 	assert.NotNil(t, repo.head)
 	assert.NotNil(t, repo.schema)
 }
+
+func TestConcurrentTransactions(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := repo.Transaction(ctx, repo.Head())
+			if err != nil {
+				errs <- err
+				return
+			}
+			_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			_, err = repo.CommitTransaction(ctx, tx, CommitInfo{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	docs, err := repo.Dump(ctx)
+	require.NoError(t, err)
+	assert.Len(t, docs["User"], 10)
+}
+
+func TestCommitTransactionConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	_, err = repo.CommitTransaction(ctx, tx, CommitInfo{})
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = txA.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": "Alice"}})
+	require.NoError(t, err)
+	err = txB.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": "Carol"}})
+	require.NoError(t, err)
+
+	head, err := repo.CommitTransaction(ctx, txA, CommitInfo{})
+	require.NoError(t, err)
+
+	_, err = repo.CommitTransaction(ctx, txB, CommitInfo{})
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, head, repo.Head())
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rodent-software/capy/object"
+)
+
+// Revert creates a new commit on the repo head that undoes the changes of the commit with the given hash.
+//
+// A MergeConflictError is returned if later commits changed the same values.
+func (r *Repository) Revert(ctx context.Context, hash object.Hash) error {
+	r.update.Lock()
+	defer r.update.Unlock()
+	tx, err := r.transaction(ctx, r.Head())
+	if err != nil {
+		return err
+	}
+	err = tx.Revert(ctx, hash)
+	if err != nil {
+		return err
+	}
+	head, err := tx.commit(ctx, CommitInfo{Message: fmt.Sprintf("Revert %s", hash)})
+	if err != nil {
+		return err
+	}
+	return r.setHead(ctx, head)
+}
+
+// CherryPick creates a new commit on the repo head that applies the changes of the commit with the given hash.
+//
+// A MergeConflictError is returned if the head changed the same values.
+func (r *Repository) CherryPick(ctx context.Context, hash object.Hash) error {
+	r.update.Lock()
+	defer r.update.Unlock()
+	tx, err := r.transaction(ctx, r.Head())
+	if err != nil {
+		return err
+	}
+	err = tx.CherryPick(ctx, hash)
+	if err != nil {
+		return err
+	}
+	head, err := tx.commit(ctx, CommitInfo{Message: fmt.Sprintf("Cherry-pick %s", hash)})
+	if err != nil {
+		return err
+	}
+	return r.setHead(ctx, head)
+}
+
+// Revert undoes the changes of the commit with the given hash in the transaction.
+//
+// Changes are computed against the first parent of the commit.
+func (t *Transaction) Revert(ctx context.Context, hash object.Hash) error {
+	commit, parent, err := t.repo.commitChanges(ctx, hash)
+	if err != nil {
+		return err
+	}
+	return t.applyChanges(ctx, commit.DataRoot, parent.DataRoot)
+}
+
+// CherryPick applies the changes of the commit with the given hash to the transaction.
+//
+// Changes are computed against the first parent of the commit.
+func (t *Transaction) CherryPick(ctx context.Context, hash object.Hash) error {
+	commit, parent, err := t.repo.commitChanges(ctx, hash)
+	if err != nil {
+		return err
+	}
+	return t.applyChanges(ctx, parent.DataRoot, commit.DataRoot)
+}
+
+// commitChanges returns the commit with the given hash and the first parent it was changed from.
+func (r *Repository) commitChanges(ctx context.Context, hash object.Hash) (*object.Commit, *object.Commit, error) {
+	commit, err := r.Commit(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(commit.Parents) == 0 {
+		return nil, nil, fmt.Errorf("commit %s has no parent", hash)
+	}
+	parent, err := r.Commit(ctx, commit.Parents[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return commit, parent, nil
+}
+
+// applyChanges merges the changes from the base data root to the target data root into the transaction.
+//
+// Values changed by both the transaction and the target are reported as a MergeConflictError.
+func (t *Transaction) applyChanges(ctx context.Context, baseHash, targetHash object.Hash) error {
+	err := t.checkWritable()
+	if err != nil {
+		return err
+	}
+	err = t.encodeCollections(ctx)
+	if err != nil {
+		return err
+	}
+	ourHash, err := t.storeObject(ctx, t.data)
+	if err != nil {
+		return err
+	}
+	m := &merger{repo: t.repo, objects: t, resolve: ErrorConflictResolver}
+	hash, err := m.mergeDataRoots(ctx, baseHash, ourHash, targetHash)
+	if err != nil {
+		return err
+	}
+	dataRoot, err := loadDataRoot(ctx, t, hash)
+	if err != nil {
+		return err
+	}
+	schema, err := t.repo.dataSchema(ctx, dataRoot)
+	if err != nil {
+		return err
+	}
+	// indexes are rebuilt from the merged data the next time they are used
+	t.data = cloneDataRoot(dataRoot)
+	t.schema = schema
+	t.refs = nil
+	t.uniques = nil
+	t.sequences = nil
+	return nil
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevert(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	bob, err := txA.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashA))
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	alice, err := txB.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashB))
+
+	err = repo.Revert(ctx, hashA)
+	require.NoError(t, err)
+
+	commit, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+	assert.Equal(t, "Revert "+hashA.String(), commit.Message)
+	require.Len(t, commit.Parents, 1)
+	assert.Equal(t, hashB, commit.Parents[0])
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	exists, err := tx.documentExists(ctx, "User", bob)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	doc, err := tx.ReadDocument(ctx, "User", alice)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", doc["name"])
+}
+
+func TestRevertConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	bob, err := txA.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashA))
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	err = txB.PatchDocument(ctx, "User", bob, map[string]any{"name": map[string]any{"set": "Robert"}})
+	require.NoError(t, err)
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashB))
+
+	err = repo.Revert(ctx, hashA)
+	var conflict *MergeConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "User", conflict.Collection)
+	assert.Equal(t, bob, conflict.ID)
+	assert.Empty(t, conflict.Path)
+	assert.Equal(t, hashB, repo.Head())
+}
+
+func TestCherryPick(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+	head := repo.Head()
+
+	txA, err := repo.Transaction(ctx, head)
+	require.NoError(t, err)
+	bob, err := txA.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, head)
+	require.NoError(t, err)
+	_, err = txB.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashB))
+
+	err = repo.CherryPick(ctx, hashA)
+	require.NoError(t, err)
+
+	commit, err := repo.Commit(ctx, repo.Head())
+	require.NoError(t, err)
+	require.Len(t, commit.Parents, 1)
+	assert.Equal(t, hashB, commit.Parents[0])
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	doc, err := tx.ReadDocument(ctx, "User", bob)
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", doc["name"])
+}
+
+func TestCherryPickConflict(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	bob, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+	head := repo.Head()
+
+	txA, err := repo.Transaction(ctx, head)
+	require.NoError(t, err)
+	err = txA.PatchDocument(ctx, "User", bob, map[string]any{"name": map[string]any{"set": "Robert"}})
+	require.NoError(t, err)
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, head)
+	require.NoError(t, err)
+	err = txB.PatchDocument(ctx, "User", bob, map[string]any{"name": map[string]any{"set": "Bobby"}})
+	require.NoError(t, err)
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hashB))
+
+	err = repo.CherryPick(ctx, hashA)
+	var conflict *MergeConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "User", conflict.Collection)
+	assert.Equal(t, bob, conflict.ID)
+	assert.Equal(t, "name", conflict.Path.String())
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var (
+	// ErrReadOnlyTransaction is returned when a read-only transaction is modified.
+	ErrReadOnlyTransaction = errors.New("transaction is read-only")
+	// ErrTransactionClosed is returned when a transaction is modified after it was rolled back.
+	ErrTransactionClosed = errors.New("transaction is closed")
+)
+
+// Savepoint identifies a state of a transaction that can be restored with RollbackTo.
+type Savepoint int
+
+// savepoint contains the transaction state captured by a Savepoint.
+type savepoint struct {
+	data      *object.DataRoot
+	schema    *ast.Schema
+	sequences map[sequenceKey]int64
+}
+
+// ReadTransaction returns a new read-only transaction based on the commit with the given hash.
+//
+// Read-only transactions return ErrReadOnlyTransaction from any operation that modifies documents.
+func (r *Repository) ReadTransaction(ctx context.Context, hash object.Hash) (*Transaction, error) {
+	tx, err := r.Transaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	tx.readOnly = true
+	return tx, nil
+}
+
+// ReadOnly returns true if the transaction rejects writes.
+func (t *Transaction) ReadOnly() bool {
+	return t.readOnly
+}
+
+// Savepoint returns a savepoint that can be used to undo all changes made after it was created.
+//
+// Savepoints can be nested. Rolling back to a savepoint discards all savepoints created after it.
+func (t *Transaction) Savepoint(ctx context.Context) (Savepoint, error) {
+	if t.closed {
+		return 0, ErrTransactionClosed
+	}
+	err := t.encodeCollections(ctx)
+	if err != nil {
+		return 0, err
+	}
+	t.savepoints = append(t.savepoints, t.savepoint())
+	return Savepoint(len(t.savepoints) - 1), nil
+}
+
+// RollbackTo undoes all changes made after the savepoint was created.
+//
+// The savepoint remains valid and can be rolled back to again.
+func (t *Transaction) RollbackTo(sp Savepoint) error {
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	if sp < 0 || int(sp) >= len(t.savepoints) {
+		return fmt.Errorf("savepoint %d does not exist", sp)
+	}
+	t.restore(t.savepoints[sp])
+	t.savepoints = t.savepoints[:sp+1]
+	return nil
+}
+
+// Rollback discards all changes made by the transaction and closes it.
+//
+// Buffered objects are dropped without being written to the repo storage.
+// The transaction can still be read but cannot be modified or committed,
+// and the objects it reads are no longer kept by GC.
+func (t *Transaction) Rollback() error {
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	t.releaseRoots()
+	t.restore(t.base)
+	t.objects = nil
+	t.savepoints = nil
+	t.closed = true
+	return nil
+}
+
+// checkWritable returns an error if the transaction cannot be modified.
+func (t *Transaction) checkWritable() error {
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	if t.readOnly {
+		return ErrReadOnlyTransaction
+	}
+	return nil
+}
+
+// savepoint returns a copy of the current transaction state.
+func (t *Transaction) savepoint() savepoint {
+	return savepoint{
+		data:      cloneDataRoot(t.data),
+		schema:    t.schema,
+		sequences: maps.Clone(t.sequences),
+	}
+}
+
+// restore replaces the transaction state with a copy of the savepoint.
+func (t *Transaction) restore(sp savepoint) {
+	// indexes are rebuilt from the restored data the next time they are used
+	t.data = cloneDataRoot(sp.data)
+	t.collections = nil
+	t.schema = sp.schema
+	t.sequences = maps.Clone(sp.sequences)
+	t.refs = nil
+	t.uniques = nil
+}
+
+// cloneDataRoot returns a copy of the data root that can be modified independently.
+func cloneDataRoot(data *object.DataRoot) *object.DataRoot {
+	if data == nil {
+		return nil
+	}
+	return &object.DataRoot{
+		Collections: maps.Clone(data.Collections),
+		Schema:      data.Schema,
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionBuffersObjects(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage().(CollectableStorage)
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+	before, err := storage.Keys(ctx)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": "Alice"}})
+	require.NoError(t, err)
+
+	keys, err := storage.Keys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, before, keys)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	opened, err := OpenRepository(ctx, storage)
+	require.NoError(t, err)
+	read, err := opened.Transaction(ctx, opened.Head())
+	require.NoError(t, err)
+	doc, err := read.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", doc["name"])
+
+	// intermediate document versions are never written
+	removed, err := repo.GC(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage().(CollectableStorage)
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+	before, err := storage.Keys(ctx)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	exists, err := tx.documentExists(ctx, "User", id)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	assert.ErrorIs(t, err, ErrTransactionClosed)
+	_, err = tx.Commit(ctx)
+	assert.ErrorIs(t, err, ErrTransactionClosed)
+	assert.ErrorIs(t, tx.Rollback(), ErrTransactionClosed)
+
+	keys, err := storage.Keys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, before, keys)
+}
+
+func TestTransactionSavepoints(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String! @unique }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	bob, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	outer, err := tx.Savepoint(ctx)
+	require.NoError(t, err)
+	alice, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+
+	inner, err := tx.Savepoint(ctx)
+	require.NoError(t, err)
+	err = tx.DeleteDocument(ctx, "User", bob)
+	require.NoError(t, err)
+
+	require.NoError(t, tx.RollbackTo(inner))
+	exists, err := tx.documentExists(ctx, "User", bob)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, tx.RollbackTo(outer))
+	exists, err = tx.documentExists(ctx, "User", alice)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// savepoints after the restored savepoint are discarded
+	assert.Error(t, tx.RollbackTo(inner))
+
+	// unique indexes are rebuilt from the restored data
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	assert.Error(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	read, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	iter, err := read.DocumentIterator(ctx, "User")
+	require.NoError(t, err)
+	var names []any
+	for !iter.Done() {
+		_, _, doc, err := iter.Next(ctx)
+		require.NoError(t, err)
+		names = append(names, doc["name"])
+	}
+	assert.ElementsMatch(t, []any{"Alice", "Bob"}, names)
+}
+
+func TestReadTransaction(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Merge(ctx, hash))
+
+	read, err := repo.ReadTransaction(ctx, repo.Head())
+	require.NoError(t, err)
+	assert.True(t, read.ReadOnly())
+	doc, err := read.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", doc["name"])
+
+	_, err = read.CreateDocument(ctx, "User", map[string]any{"name": "Alice"})
+	assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+	err = read.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": "Alice"}})
+	assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+	err = read.DeleteDocument(ctx, "User", id)
+	assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+	err = read.Revert(ctx, hash)
+	assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+	_, err = read.Commit(ctx)
+	assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+}
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// dateTimeScalar is a date and time stored as a UTC time.Time.
+	dateTimeScalar = "DateTime"
+	// dateScalar is a calendar date stored as a UTC time.Time at midnight.
+	dateScalar = "Date"
+	// jsonScalar is an arbitrary JSON value.
+	jsonScalar = "JSON"
+	// bytesScalar is binary data stored as a []byte.
+	bytesScalar = "Bytes"
+	// decimalScalar is an arbitrary precision decimal stored as a *big.Rat.
+	decimalScalar = "Decimal"
+	// bigIntScalar is an arbitrary precision integer stored as a *big.Int.
+	bigIntScalar = "BigInt"
+)
+
+// dateLayout is the layout used to parse and format dates.
+const dateLayout = time.DateOnly
+
+// validScalar returns true if the value is a valid representation of the given scalar or enum definition.
+func validScalar(def *ast.Definition, value any) bool {
+	switch def.Name {
+	case "Int":
+		_, ok := value.(int64)
+		return ok
+	case "Float":
+		_, ok := value.(float64)
+		return ok
+	case "String", "ID":
+		_, ok := value.(string)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	case dateTimeScalar, dateScalar:
+		_, ok := value.(time.Time)
+		return ok
+	case bytesScalar:
+		_, ok := value.([]byte)
+		return ok
+	case decimalScalar:
+		_, ok := value.(*big.Rat)
+		return ok
+	case bigIntScalar:
+		_, ok := value.(*big.Int)
+		return ok
+	case jsonScalar:
+		return validJSON(value)
+	}
+	if def.Kind == ast.Enum {
+		return enumIndex(def, value) >= 0
+	}
+	return true
+}
+
+// validJSON returns true if the value only contains JSON compatible values.
+func validJSON(value any) bool {
+	switch v := value.(type) {
+	case nil, string, bool, int64, float64:
+		return true
+	case []any:
+		for _, e := range v {
+			if !validJSON(e) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		for _, e := range v {
+			if !validJSON(e) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// coerceInt returns the value converted to the representation used by the Int scalar.
+//
+// All Go integer kinds are accepted as well as floats without a fractional part.
+func coerceInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float32:
+		return int64(v), float32(int64(v)) == v
+	case float64:
+		return int64(v), float64(int64(v)) == v
+	default:
+		return 0, false
+	}
+}
+
+// coerceFloat returns the value converted to the representation used by the Float scalar.
+//
+// All Go integer and float kinds are accepted.
+func coerceFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	if i, ok := coerceInt(value); ok {
+		return float64(i), true
+	}
+	switch v := value.(type) {
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// coerceScalar returns the value converted to the representation used by the given scalar.
+//
+// Values that cannot be converted are returned unchanged.
+func coerceScalar(name string, value any) any {
+	switch name {
+	case "Int":
+		if v, ok := coerceInt(value); ok {
+			return v
+		}
+	case "Float":
+		if v, ok := coerceFloat(value); ok {
+			return v
+		}
+	case dateTimeScalar:
+		switch v := value.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err == nil {
+				return t.UTC()
+			}
+		case time.Time:
+			return v.UTC()
+		}
+	case dateScalar:
+		switch v := value.(type) {
+		case string:
+			t, err := time.Parse(dateLayout, v)
+			if err == nil {
+				return t
+			}
+		case time.Time:
+			return time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		}
+	case bytesScalar:
+		if v, ok := value.(string); ok {
+			data, err := base64.StdEncoding.DecodeString(v)
+			if err == nil {
+				return data
+			}
+		}
+	case decimalScalar:
+		switch v := value.(type) {
+		case string:
+			if r, ok := new(big.Rat).SetString(v); ok && !strings.Contains(v, "/") {
+				return r
+			}
+		case int64:
+			return new(big.Rat).SetInt64(v)
+		case float64:
+			if r, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'f', -1, 64)); ok {
+				return r
+			}
+		}
+	case bigIntScalar:
+		switch v := value.(type) {
+		case string:
+			if i, ok := new(big.Int).SetString(v, 10); ok {
+				return i
+			}
+		case int64:
+			return big.NewInt(v)
+		case float64:
+			if v == float64(int64(v)) {
+				return big.NewInt(int64(v))
+			}
+		}
+	case jsonScalar:
+		return coerceJSON(value)
+	}
+	return value
+}
+
+// coerceJSON returns the value with integers converted to the representation used by documents.
+func coerceJSON(value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = coerceJSON(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = coerceJSON(e)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// coerceFilter returns the filter operand converted to the representation used by the given scalar.
+func coerceFilter(name string, op string, value any) any {
+	switch op {
+	case inFilter, notInFilter:
+		list, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		out := make([]any, len(list))
+		for i, v := range list {
+			out[i] = coerceScalar(name, v)
+		}
+		return out
+	case equalFilter, notEqualFilter, greaterFilter, greaterOrEqualFilter, lessFilter, lessOrEqualFilter:
+		return coerceScalar(name, value)
+	default:
+		return value
+	}
+}
+
+// valuesEqual returns true if the document values are equal.
+func valuesEqual(a, b any) bool {
+	switch v := a.(type) {
+	case time.Time:
+		t, ok := b.(time.Time)
+		return ok && v.Equal(t)
+	case []byte:
+		d, ok := b.([]byte)
+		return ok && bytes.Equal(v, d)
+	case *big.Int:
+		i, ok := b.(*big.Int)
+		return ok && v.Cmp(i) == 0
+	case *big.Rat:
+		r, ok := b.(*big.Rat)
+		return ok && v.Cmp(r) == 0
+	case []any:
+		l, ok := b.([]any)
+		if !ok || len(v) != len(l) {
+			return false
+		}
+		for i := range v {
+			if !valuesEqual(v[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		m, ok := b.(map[string]any)
+		if !ok || len(v) != len(m) {
+			return false
+		}
+		for k := range v {
+			e, ok := m[k]
+			if !ok || !valuesEqual(v[k], e) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// containsValue returns true if the list contains a value equal to the given value.
+func containsValue(list []any, value any) bool {
+	for _, v := range list {
+		if valuesEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterCompare(value any, filter any) (int, error) {
+	switch v := value.(type) {
+	case int64:
+		return compareOrdered(v, filter)
+	case float64:
+		return compareOrdered(v, filter)
+	case string:
+		return compareOrdered(v, filter)
+	case time.Time:
+		f, ok := filter.(time.Time)
+		if !ok {
+			return 0, fmt.Errorf("invalid value for compare filter %v", filter)
+		}
+		return v.Compare(f), nil
+	case []byte:
+		f, ok := filter.([]byte)
+		if !ok {
+			return 0, fmt.Errorf("invalid value for compare filter %v", filter)
+		}
+		return bytes.Compare(v, f), nil
+	case *big.Int:
+		f, ok := filter.(*big.Int)
+		if !ok {
+			return 0, fmt.Errorf("invalid value for compare filter %v", filter)
+		}
+		return v.Cmp(f), nil
+	case *big.Rat:
+		f, ok := filter.(*big.Rat)
+		if !ok {
+			return 0, fmt.Errorf("invalid value for compare filter %v", filter)
+		}
+		return v.Cmp(f), nil
+	default:
+		return 0, fmt.Errorf("invalid kind for compare filter")
+	}
+}
+
+// compareOrdered compares the value to the filter value when they are the same type.
+func compareOrdered[T cmp.Ordered](value T, filter any) (int, error) {
+	f, ok := filter.(T)
+	if !ok {
+		return 0, fmt.Errorf("invalid value for compare filter %v", filter)
+	}
+	return cmp.Compare(value, f), nil
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomScalars(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type Event {
+		at: DateTime
+		day: Date
+		data: JSON
+		payload: Bytes
+		price: Decimal
+		count: BigInt
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Event", map[string]any{
+		"at":      "2024-01-01T10:00:00+02:00",
+		"day":     time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC),
+		"data":    map[string]any{"n": nil, "count": 1},
+		"payload": "aGVsbG8=",
+		"price":   "10.50",
+		"count":   int64(7),
+	})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "Event", id)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), doc["at"])
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), doc["day"])
+	assert.Equal(t, map[string]any{"n": nil, "count": int64(1)}, doc["data"])
+	assert.Equal(t, []byte("hello"), doc["payload"])
+	assert.Equal(t, 0, big.NewRat(21, 2).Cmp(doc["price"].(*big.Rat)))
+	assert.Equal(t, 0, big.NewInt(7).Cmp(doc["count"].(*big.Int)))
+
+	match, err := tx.FilterDocument(ctx, "Event", id, map[string]any{
+		"at":    map[string]any{"lt": "2024-01-02T00:00:00Z"},
+		"price": map[string]any{"eq": "10.5"},
+		"count": map[string]any{"in": []any{"7", "8"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	var verr *ValidationError
+	_, err = tx.CreateDocument(ctx, "Event", map[string]any{"at": "yesterday"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "at", verr.Path.String())
+
+	_, err = tx.CreateDocument(ctx, "Event", map[string]any{"price": "1/3"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "price", verr.Path.String())
+}
+
+func TestNumericCoercion(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Counter { count: Int, ratio: Float }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	// the same Go integer kinds are accepted on create and patch
+	id, err := tx.CreateDocument(ctx, "Counter", map[string]any{"count": int(1), "ratio": int32(2)})
+	require.NoError(t, err)
+	err = tx.PatchDocument(ctx, "Counter", id, map[string]any{
+		"count": map[string]any{"increment": int(2)},
+		"ratio": map[string]any{"multiply": uint8(3)},
+	})
+	require.NoError(t, err)
+	doc, err := tx.ReadDocument(ctx, "Counter", id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), doc["count"])
+	assert.Equal(t, float64(6), doc["ratio"])
+
+	err = tx.PatchDocument(ctx, "Counter", id, map[string]any{"count": map[string]any{"increment": 1.5}})
+	assert.Error(t, err)
+}
+
+func TestNumericPatchOverflow(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Counter { count: Int, ratio: Float }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Counter", map[string]any{"count": int64(math.MaxInt64 - 1), "ratio": math.MaxFloat64})
+	require.NoError(t, err)
+
+	patches := []map[string]any{
+		{"count": map[string]any{"increment": 2}},
+		{"count": map[string]any{"decrement": math.MinInt64}},
+		{"count": map[string]any{"multiply": 2}},
+		{"ratio": map[string]any{"multiply": 2}},
+		{"ratio": map[string]any{"decrement": -math.MaxFloat64}},
+	}
+	for _, patch := range patches {
+		var verr *ValidationError
+		err = tx.PatchDocument(ctx, "Counter", id, patch)
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, RangeErrorCode, verr.Code)
+	}
+
+	err = tx.PatchDocument(ctx, "Counter", id, map[string]any{"count": map[string]any{"increment": 1}})
+	require.NoError(t, err)
+	doc, err := tx.ReadDocument(ctx, "Counter", id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), doc["count"])
+	assert.Equal(t, math.MaxFloat64, doc["ratio"])
+}
+
+func TestJSONMerge(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Event { data: JSON }`
+
+	repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Event", map[string]any{"data": map[string]any{"a": 1, "b": 1}})
+	require.NoError(t, err)
+
+	hash, err := tx.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hash)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = txA.PatchDocument(ctx, "Event", id, map[string]any{"data": map[string]any{"set": map[string]any{"a": 2, "b": 1}}})
+	require.NoError(t, err)
+
+	hashA, err := txA.Commit(ctx)
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	err = txB.PatchDocument(ctx, "Event", id, map[string]any{"data": map[string]any{"set": map[string]any{"a": 1, "b": 2}}})
+	require.NoError(t, err)
+
+	hashB, err := txB.Commit(ctx)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hashA)
+	require.NoError(t, err)
+
+	err = repo.Merge(ctx, hashB)
+	require.NoError(t, err)
+
+	// JSON values are resolved as a whole instead of merged key by key
+	txC, err := repo.Transaction(ctx, repo.Head())
+	require.NoError(t, err)
+
+	doc, err := txC.ReadDocument(ctx, "Event", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": int64(1), "b": int64(2)}, doc["data"])
+}
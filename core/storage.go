@@ -18,6 +18,32 @@ type Storage interface {
 	Put(ctx context.Context, key string, value []byte) error
 }
 
+// CollectableStorage is a Storage that can list and remove keys.
+//
+// Storage backends must implement it to support garbage collection.
+type CollectableStorage interface {
+	Storage
+	// Keys returns all keys contained in the storage.
+	Keys(ctx context.Context) ([]string, error)
+	// Delete removes the value with the given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// hashObject returns the hash of the encoded object without storing it.
+func hashObject(value any) (object.Hash, error) {
+	hash := sha3.New256()
+	enc := codec.NewEncoder(hash)
+	err := enc.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return object.Hash(hash.Sum(nil)), nil
+}
+
 // EncodeObject writes an encoded object to the given storage and returns its hash.
 //
 // The key for the object is the computed hash of the encoded bytes.
@@ -1,8 +1,13 @@
 package core
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
+// memoryStorage is a Storage that keeps values in memory and is safe for concurrent use.
 type memoryStorage struct {
+	mu     sync.RWMutex
 	values map[string][]byte
 }
 
@@ -13,6 +18,8 @@ func NewMemoryStorage() Storage {
 }
 
 func (m *memoryStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	content, ok := m.values[key]
 	if !ok {
 		return nil, ErrNotFound
@@ -25,6 +32,25 @@ func (m *memoryStorage) Get(ctx context.Context, key string) ([]byte, error) {
 func (m *memoryStorage) Put(ctx context.Context, key string, value []byte) error {
 	val := make([]byte, len(value))
 	copy(val, value)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.values[key] = val
 	return nil
 }
+
+func (m *memoryStorage) Keys(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
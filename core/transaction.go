@@ -1,14 +1,14 @@
 package core
 
 import (
-	"cmp"
 	"context"
 	"fmt"
+	"math"
 	"slices"
 
+	"github.com/rodent-software/capy/graphql/schema_gen"
 	"github.com/rodent-software/capy/object"
 
-	"github.com/google/uuid"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -19,6 +19,36 @@ const (
 	appendPatch = "append"
 	// filterPatch is a patch operation that filters a list value.
 	filterPatch = "filter"
+	// prependPatch is a patch operation that prepends values to a list field.
+	prependPatch = "prepend"
+	// insertAtPatch is a patch operation that inserts values into a list field at an index.
+	insertAtPatch = "insertAt"
+	// removeAtPatch is a patch operation that removes the value at an index from a list field.
+	removeAtPatch = "removeAt"
+	// addToSetPatch is a patch operation that appends values not already contained in a list field.
+	addToSetPatch = "addToSet"
+	// removeValuesPatch is a patch operation that removes all matching values from a list field.
+	removeValuesPatch = "removeValues"
+	// unsetPatch is a patch operation that removes a field value.
+	unsetPatch = "unset"
+	// incrementPatch is a patch operation that adds to a numeric field.
+	incrementPatch = "increment"
+	// decrementPatch is a patch operation that subtracts from a numeric field.
+	decrementPatch = "decrement"
+	// multiplyPatch is a patch operation that multiplies a numeric field.
+	multiplyPatch = "multiply"
+	// minPatch is a patch operation that sets a numeric field to the lesser value.
+	minPatch = "min"
+	// maxPatch is a patch operation that sets a numeric field to the greater value.
+	maxPatch = "max"
+	// connectPatch is a patch operation that relates existing documents.
+	connectPatch = "connect"
+	// disconnectPatch is a patch operation that removes related documents.
+	disconnectPatch = "disconnect"
+	// createPatch is a patch operation that relates new documents.
+	createPatch = "create"
+	// updatePatch is a patch operation that patches related documents.
+	updatePatch = "update"
 	// equalFilter matches if the target value is equal to the filter value.
 	equalFilter = "eq"
 	// notEqualFilter matches if the target value is not equal to the filter value.
@@ -51,13 +81,48 @@ const (
 
 // Transaction is used to create, read, and update documents.
 type Transaction struct {
-	repo *Repository
-	data *object.DataRoot
-	hash object.Hash
+	repo      *Repository
+	schema    *ast.Schema
+	data      *object.DataRoot
+	hash      object.Hash
+	refs      *referenceIndex
+	uniques   *uniqueIndex
+	sequences map[sequenceKey]int64
+	// objects contains the encoded objects written by the transaction that have not been committed.
+	objects overlayStorage
+	// collections contains the modified collections that have not been encoded.
+	collections map[string]*object.Collection
+	// base is the state restored when the transaction is rolled back.
+	base       savepoint
+	savepoints []savepoint
+	readOnly   bool
+	closed     bool
+
+	// sharedRefs and sharedUniques are true when the indexes are shared by the repo and must be copied before they are modified.
+	sharedRefs    bool
+	sharedUniques bool
+	// roots contains the commits kept by GC until the transaction is committed or rolled back.
+	roots []object.Hash
 }
 
 // Transactions returns a new transaction based on the commit with the given hash.
+//
+// The objects of the commit are kept by GC until the transaction is committed or rolled back.
 func (r *Repository) Transaction(ctx context.Context, hash object.Hash) (*Transaction, error) {
+	r.retainRoot(hash)
+	tx, err := r.transaction(ctx, hash)
+	if err != nil {
+		r.releaseRoot(hash)
+		return nil, err
+	}
+	tx.roots = []object.Hash{hash}
+	return tx, nil
+}
+
+// transaction returns a new transaction based on the commit with the given hash that is not kept by GC.
+//
+// It is used by operations that hold the update lock for the lifetime of the transaction.
+func (r *Repository) transaction(ctx context.Context, hash object.Hash) (*Transaction, error) {
 	commit, err := r.Commit(ctx, hash)
 	if err != nil {
 		return nil, err
@@ -66,33 +131,93 @@ func (r *Repository) Transaction(ctx context.Context, hash object.Hash) (*Transa
 	if err != nil {
 		return nil, err
 	}
-	return &Transaction{
-		repo: r,
-		data: dataRoot,
-		hash: hash,
-	}, nil
+	schema, err := r.dataSchema(ctx, dataRoot)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Transaction{
+		repo:   r,
+		schema: schema,
+		data:   cloneDataRoot(dataRoot),
+		hash:   hash,
+	}
+	tx.base = tx.savepoint()
+	return tx, nil
+}
+
+// Repository returns the repo the transaction belongs to.
+func (t *Transaction) Repository() *Repository {
+	return t.repo
+}
+
+// Schema returns the schema that describes the collections of the transaction.
+func (t *Transaction) Schema() *ast.Schema {
+	return t.schema
+}
+
+// CommitInfo contains metadata describing a new commit.
+type CommitInfo struct {
+	// Author identifies who created the commit.
+	Author string
+	// Message describes the changes contained in the commit.
+	Message string
 }
 
 // Commit creates a new commit containing the transaction data.
 func (t *Transaction) Commit(ctx context.Context) (object.Hash, error) {
-	data, err := EncodeObject(ctx, t.repo.storage, t.data)
+	return t.CommitWithInfo(ctx, CommitInfo{})
+}
+
+// CommitWithInfo creates a new commit containing the transaction data and the given metadata.
+//
+// Objects buffered by the transaction are written to the repo storage before the commit.
+// The commit is written while head updates are blocked, and like other commits it is
+// removed by GC if it is not merged before GC runs.
+func (t *Transaction) CommitWithInfo(ctx context.Context, info CommitInfo) (object.Hash, error) {
+	t.repo.update.Lock()
+	defer t.repo.update.Unlock()
+	hash, err := t.commit(ctx, info)
 	if err != nil {
 		return nil, err
 	}
+	t.releaseRoots()
+	return hash, nil
+}
+
+// commit creates a new commit containing the transaction data and the given metadata.
+//
+// The caller must hold the update lock so that GC does not remove the written objects.
+func (t *Transaction) commit(ctx context.Context, info CommitInfo) (object.Hash, error) {
+	err := t.checkWritable()
+	if err != nil {
+		return nil, err
+	}
+	err = t.encodeCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = t.flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.repo.storeObject(ctx, t.data)
+	if err != nil {
+		return nil, err
+	}
+	t.shareIndexes(data)
 	commit := &object.Commit{
 		Parents:  []object.Hash{t.hash},
 		DataRoot: data,
+		Time:     t.repo.clock().UTC(),
+		Author:   info.Author,
+		Message:  info.Message,
 	}
-	return EncodeObject(ctx, t.repo.storage, commit)
+	return t.repo.storeObject(ctx, commit)
 }
 
 // ReadDocument returns the document from the given collection with the matching id.
 func (t *Transaction) ReadDocument(ctx context.Context, collection, id string) (map[string]any, error) {
-	colHash, ok := t.data.Collections[collection]
-	if !ok {
-		return nil, fmt.Errorf("collection does not exist: %s", collection)
-	}
-	col, err := t.repo.Collection(ctx, colHash)
+	col, err := t.collection(ctx, collection)
 	if err != nil {
 		return nil, err
 	}
@@ -100,70 +225,179 @@ func (t *Transaction) ReadDocument(ctx context.Context, collection, id string) (
 	if !ok {
 		return nil, fmt.Errorf("document not found")
 	}
-	return t.repo.Document(ctx, docHash)
+	return loadDocument(ctx, t, docHash)
+}
+
+// ReadDocuments returns the hashes and documents from the given collection with the matching ids.
+//
+// The collection is loaded once for all of the documents.
+// Documents that do not exist are returned as nil with a nil hash.
+func (t *Transaction) ReadDocuments(ctx context.Context, collection string, ids []string) ([]object.Hash, []map[string]any, error) {
+	col, err := t.collection(ctx, collection)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]object.Hash, 0, len(ids))
+	docs := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		docHash, ok := col.Documents[id]
+		if !ok {
+			hashes = append(hashes, nil)
+			docs = append(docs, nil)
+			continue
+		}
+		doc, err := loadDocument(ctx, t, docHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes = append(hashes, docHash)
+		docs = append(docs, doc)
+	}
+	return hashes, docs, nil
+}
+
+// documentExists returns true if the given collection contains a document with a matching id.
+func (t *Transaction) documentExists(ctx context.Context, collection, id string) (bool, error) {
+	col, err := t.collection(ctx, collection)
+	if err != nil {
+		return false, err
+	}
+	_, ok := col.Documents[id]
+	return ok, nil
 }
 
 // DeleteDocument deletes the document from the given collection with the matching id.
+//
+// Relations referencing the document are updated according to their delete action.
+// If any action fails the transaction is left unchanged.
 func (t *Transaction) DeleteDocument(ctx context.Context, collection, id string) error {
-	colHash, ok := t.data.Collections[collection]
-	if !ok {
-		return fmt.Errorf("collection does not exist: %s", collection)
+	err := t.checkWritable()
+	if err != nil {
+		return err
 	}
-	col, err := t.repo.Collection(ctx, colHash)
+	err = t.encodeCollections(ctx)
 	if err != nil {
 		return err
 	}
-	delete(col.Documents, id)
-	colHash, err = EncodeObject(ctx, t.repo.storage, col)
+	sp := t.savepoint()
+	err = t.deleteDocument(ctx, collection, id)
+	if err != nil {
+		t.restore(sp)
+	}
+	return err
+}
+
+// deleteDocument deletes the document and applies the delete actions of the relations referencing it.
+func (t *Transaction) deleteDocument(ctx context.Context, collection, id string) error {
+	exists, err := t.documentExists(ctx, collection, id)
 	if err != nil {
 		return err
 	}
-	t.data.Collections[collection] = colHash
+	if !exists {
+		return fmt.Errorf("document not found %s", id)
+	}
+	refs, err := t.references(ctx, collection, id)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if deleteAction(ref.field) == restrictAction && !ref.is(collection, id) {
+			return fmt.Errorf("document %s is referenced by %s %s", id, ref.collection, ref.id)
+		}
+	}
+	err = t.removeDocument(ctx, collection, id)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if ref.is(collection, id) {
+			continue // ignore self references
+		}
+		err = t.deleteReference(ctx, ref, collection, id)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// writeDocument stores the document and updates the collection to reference it.
+func (t *Transaction) writeDocument(ctx context.Context, collection, id string, doc object.Document) error {
+	err := t.checkWritable()
+	if err != nil {
+		return err
+	}
+	col, err := t.modifyCollection(ctx, collection)
+	if err != nil {
+		return err
+	}
+	docHash, err := t.storeObject(ctx, doc)
+	if err != nil {
+		return err
+	}
+	col.Documents[id] = docHash
+	return t.updateIndexes(collection, id, doc)
+}
+
+// removeDocument removes the document from the collection.
+func (t *Transaction) removeDocument(ctx context.Context, collection, id string) error {
+	err := t.checkWritable()
+	if err != nil {
+		return err
+	}
+	col, err := t.modifyCollection(ctx, collection)
+	if err != nil {
+		return err
+	}
+	delete(col.Documents, id)
+	return t.updateIndexes(collection, id, nil)
+}
+
 // CreateDocument adds a document to the given collection and returns its unique id.
+//
+// If the value contains an id it is used instead of generating one.
 func (t *Transaction) CreateDocument(ctx context.Context, collection string, value map[string]any) (string, error) {
-	def, ok := t.repo.schema.Types[collection]
-	if !ok || def.BuiltIn || def.Kind != ast.Object {
+	def, ok := t.schema.Types[collection]
+	if !ok || !schema_gen.IsCollection(def) {
 		return "", fmt.Errorf("collection does not exist: %s", collection)
 	}
 	doc, err := t.createDocument(ctx, def, value)
 	if err != nil {
 		return "", err
 	}
-	id, err := uuid.NewRandom()
+	err = t.createGenerated(ctx, def, doc)
 	if err != nil {
 		return "", err
 	}
-	docHash, err := EncodeObject(ctx, t.repo.storage, doc)
+	err = t.validateDocument(ctx, def, doc)
 	if err != nil {
 		return "", err
 	}
-	colHash, ok := t.data.Collections[collection]
-	if !ok {
-		return "", fmt.Errorf("collection does not exist: %s", collection)
+	id, err := t.createID(def, doc, value["id"])
+	if err != nil {
+		return "", err
 	}
-	col, err := t.repo.Collection(ctx, colHash)
+	exists, err := t.documentExists(ctx, collection, id)
 	if err != nil {
 		return "", err
 	}
-	col.Documents[id.String()] = docHash
-	colHash, err = EncodeObject(ctx, t.repo.storage, col)
+	if exists {
+		return "", fmt.Errorf("document already exists %s", id)
+	}
+	err = t.checkUnique(ctx, def, id, doc)
 	if err != nil {
 		return "", err
 	}
-	t.data.Collections[collection] = colHash
-	return id.String(), nil
+	err = t.writeDocument(ctx, collection, id, doc)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
 }
 
 // FilterDocument returns a bool indicating if the document in the given collection with matching id passes the given filter.
 func (t *Transaction) FilterDocument(ctx context.Context, collection, id string, filter any) (bool, error) {
-	colHash, ok := t.data.Collections[collection]
-	if !ok {
-		return false, fmt.Errorf("collection does not exist: %s", collection)
-	}
-	col, err := t.repo.Collection(ctx, colHash)
+	col, err := t.collection(ctx, collection)
 	if err != nil {
 		return false, err
 	}
@@ -171,21 +405,31 @@ func (t *Transaction) FilterDocument(ctx context.Context, collection, id string,
 	if !ok {
 		return false, fmt.Errorf("document not found %s", id)
 	}
-	doc, err := t.repo.Document(ctx, docHash)
+	doc, err := loadDocument(ctx, t, docHash)
 	if err != nil {
 		return false, err
 	}
-	def := t.repo.schema.Types[collection]
-	return t.filterDocument(ctx, def, doc, filter)
+	def := t.schema.Types[collection]
+	return t.filterDocument(ctx, def, id, doc, filter)
+}
+
+// MatchDocument returns a bool indicating if the document from the given collection with matching id passes the given filter.
+//
+// It avoids reading the document again when the caller has already loaded it.
+func (t *Transaction) MatchDocument(ctx context.Context, collection, id string, doc map[string]any, filter any) (bool, error) {
+	def, ok := t.schema.Types[collection]
+	if !ok {
+		return false, fmt.Errorf("collection does not exist: %s", collection)
+	}
+	return t.filterDocument(ctx, def, id, doc, filter)
 }
 
 // PatchDocument updates the document in the given collection with matching id by applying the operations in the patch.
 func (t *Transaction) PatchDocument(ctx context.Context, collection, id string, patch map[string]any) error {
-	colHash, ok := t.data.Collections[collection]
-	if !ok {
-		return fmt.Errorf("collection does not exist: %s", collection)
+	if op, ok := relationPatchOp(patch); ok {
+		return fmt.Errorf("relation patch operation %s is only valid on relation fields", op)
 	}
-	col, err := t.repo.Collection(ctx, colHash)
+	col, err := t.collection(ctx, collection)
 	if err != nil {
 		return err
 	}
@@ -193,38 +437,69 @@ func (t *Transaction) PatchDocument(ctx context.Context, collection, id string,
 	if !ok {
 		return fmt.Errorf("document not found %s", id)
 	}
-	doc, err := t.repo.Document(ctx, docHash)
+	doc, err := loadDocument(ctx, t, docHash)
 	if err != nil {
 		return err
 	}
-	def := t.repo.schema.Types[collection]
+	def := t.schema.Types[collection]
 	doc, err = t.patchDocument(ctx, def, doc, patch)
 	if err != nil {
 		return err
 	}
-	docHash, err = EncodeObject(ctx, t.repo.storage, doc)
+	t.patchGenerated(def, doc, patch)
+	err = t.validateDocument(ctx, def, doc)
 	if err != nil {
 		return err
 	}
-	col.Documents[id] = docHash
-	colHash, err = EncodeObject(ctx, t.repo.storage, col)
+	err = t.checkUnique(ctx, def, id, doc)
 	if err != nil {
 		return err
 	}
-	t.data.Collections[collection] = colHash
-	return nil
+	return t.writeDocument(ctx, collection, id, doc)
+}
+
+// createID returns the id for a new document in the collection described by def.
+func (t *Transaction) createID(def *ast.Definition, doc object.Document, value any) (string, error) {
+	if value == nil {
+		return documentID(def, doc)
+	}
+	id, ok := value.(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("invalid document id %v", value)
+	}
+	strategy, _ := idStrategy(def)
+	if strategy != hashStrategy {
+		return id, nil
+	}
+	expect, err := documentID(def, doc)
+	if err != nil {
+		return "", err
+	}
+	if id != expect {
+		return "", fmt.Errorf("document id does not match content hash %s", id)
+	}
+	return id, nil
 }
 
 func (t *Transaction) createDocument(ctx context.Context, def *ast.Definition, value map[string]any) (object.Document, error) {
 	out := make(map[string]any)
 	for k, v := range value {
+		if schema_gen.IsSystemField(k) {
+			continue // ignore system fields
+		}
 		field := def.Fields.ForName(k)
 		if field == nil {
-			return nil, fmt.Errorf("invalid document field %s", k)
+			return nil, &ValidationError{Path: ast.Path{ast.PathName(k)}, Code: TypeErrorCode, Message: "unknown field"}
+		}
+		if _, ok := schema_gen.InverseOf(field); ok {
+			return nil, &ValidationError{Path: ast.Path{ast.PathName(k)}, Code: TypeErrorCode, Message: "cannot set inverse relation field"}
 		}
 		norm, err := t.createValue(ctx, field.Type, v)
 		if err != nil {
-			return nil, err
+			return nil, prefixPath(err, ast.PathName(k))
+		}
+		if norm == nil {
+			continue // ignore empty fields
 		}
 		out[k] = norm
 	}
@@ -232,15 +507,32 @@ func (t *Transaction) createDocument(ctx context.Context, def *ast.Definition, v
 }
 
 func (t *Transaction) createValue(ctx context.Context, typ *ast.Type, value any) (any, error) {
-	if !typ.NonNull && value == nil {
+	if value == nil {
 		return nil, nil
 	}
 	if typ.Elem != nil {
-		return t.createList(ctx, typ.Elem, value.([]any))
+		return t.createList(ctx, typ.Elem, patchList(value))
+	}
+	def, ok := t.schema.Types[typ.NamedType]
+	if !ok {
+		return nil, newValidationError(TypeErrorCode, "unknown type %s", typ.NamedType)
+	}
+	if schema_gen.IsEmbedded(def) {
+		return t.createEmbedded(ctx, def, value)
+	}
+	if schema_gen.IsAbstract(def) {
+		return t.createAbstract(ctx, def, value)
 	}
-	def := t.repo.schema.Types[typ.NamedType]
 	if def.Kind == ast.Object {
-		return t.createRelation(ctx, typ, value.(map[string]any))
+		input, ok := value.(map[string]any)
+		if !ok {
+			return nil, newValidationError(TypeErrorCode, "expected an object")
+		}
+		return t.createRelation(ctx, typ, input)
+	}
+	value = coerceScalar(def.Name, value)
+	if !validScalar(def, value) {
+		return nil, newValidationError(TypeErrorCode, "expected a value of type %s", def.Name)
 	}
 	return value, nil
 }
@@ -250,7 +542,7 @@ func (t *Transaction) createList(ctx context.Context, typ *ast.Type, value []any
 	for i, v := range value {
 		norm, err := t.createValue(ctx, typ, v)
 		if err != nil {
-			return nil, err
+			return nil, prefixPath(err, ast.PathIndex(i))
 		}
 		out[i] = norm
 	}
@@ -258,9 +550,9 @@ func (t *Transaction) createList(ctx context.Context, typ *ast.Type, value []any
 }
 
 func (t *Transaction) createRelation(ctx context.Context, typ *ast.Type, value map[string]any) (string, error) {
-	id, ok := value["id"].(string)
-	if ok {
-		return id, nil
+	id, ok := value["id"]
+	if ok && len(value) == 1 {
+		return t.connectRelation(ctx, typ, id) // link to an existing document
 	}
 	return t.CreateDocument(ctx, typ.NamedType, value)
 }
@@ -268,7 +560,7 @@ func (t *Transaction) createRelation(ctx context.Context, typ *ast.Type, value m
 func (t *Transaction) patchDocument(ctx context.Context, def *ast.Definition, value map[string]any, patch map[string]any) (map[string]any, error) {
 	out := make(map[string]any)
 	for _, field := range def.Fields {
-		if field.Name == "hash" || field.Name == "id" {
+		if schema_gen.IsSystemField(field.Name) {
 			continue // ignore system fields
 		}
 		v, hasValue := value[field.Name]
@@ -276,9 +568,15 @@ func (t *Transaction) patchDocument(ctx context.Context, def *ast.Definition, va
 		if !hasValue && !hasPatch {
 			continue // ignore empty fields
 		}
+		if _, ok := schema_gen.InverseOf(field); ok && hasPatch {
+			return nil, &ValidationError{Path: ast.Path{ast.PathName(field.Name)}, Code: TypeErrorCode, Message: "cannot set inverse relation field"}
+		}
 		res, err := t.patchValue(ctx, field.Type, v, p)
 		if err != nil {
-			return nil, err
+			return nil, prefixPath(err, ast.PathName(field.Name))
+		}
+		if res == nil {
+			continue // ignore unset fields
 		}
 		out[field.Name] = res
 	}
@@ -289,12 +587,18 @@ func (t *Transaction) patchValue(ctx context.Context, typ *ast.Type, value any,
 	if patch == nil {
 		return value, nil
 	}
-	def, ok := t.repo.schema.Types[typ.NamedType]
+	def, ok := t.schema.Types[typ.NamedType]
+	if ok && schema_gen.IsEmbedded(def) {
+		return t.patchEmbedded(ctx, def, typ, value, patch)
+	}
+	if ok && schema_gen.IsAbstract(def) {
+		return t.patchAbstract(ctx, def, value, patch)
+	}
 	if ok && def.Kind == ast.Object {
 		return t.patchRelation(ctx, typ, value, patch)
 	}
-	p := patch.(map[string]any)
-	if len(p) != 1 {
+	p, ok := patch.(map[string]any)
+	if !ok || len(p) != 1 {
 		return nil, fmt.Errorf("patch must contain exactly one operation")
 	}
 	var op string
@@ -304,18 +608,88 @@ func (t *Transaction) patchValue(ctx context.Context, typ *ast.Type, value any,
 	switch op {
 	case setPatch:
 		return t.createValue(ctx, typ, p[op])
+	case unsetPatch:
+		if p[op] == true {
+			return nil, nil
+		}
+		return value, nil
+	case incrementPatch, decrementPatch, multiplyPatch, minPatch, maxPatch:
+		return patchNumber(typ, op, value, p[op])
 	case appendPatch:
-		n, err := t.createValue(ctx, typ.Elem, p[op])
+		values, err := t.createList(ctx, typ.Elem, patchList(p[op]))
+		if err != nil {
+			return nil, err
+		}
+		list, _ := value.([]any)
+		return append(slices.Clone(list), values...), nil
+	case prependPatch:
+		values, err := t.createList(ctx, typ.Elem, patchList(p[op]))
+		if err != nil {
+			return nil, err
+		}
+		list, _ := value.([]any)
+		return append(values, list...), nil
+	case insertAtPatch:
+		arg, _ := p[op].(map[string]any)
+		values, err := t.createList(ctx, typ.Elem, patchList(arg["values"]))
 		if err != nil {
 			return nil, err
 		}
-		if value == nil {
-			return []any{n}, nil
+		list, _ := value.([]any)
+		index, ok := arg["index"].(int64)
+		if !ok || index < 0 || index > int64(len(list)) {
+			return nil, fmt.Errorf("index out of range %v", arg["index"])
 		}
-		return append(value.([]any), n), nil
+		return slices.Insert(slices.Clone(list), int(index), values...), nil
+	case removeAtPatch:
+		list, _ := value.([]any)
+		index, ok := p[op].(int64)
+		if !ok || index < 0 || index >= int64(len(list)) {
+			return nil, fmt.Errorf("index out of range %v", p[op])
+		}
+		return slices.Delete(slices.Clone(list), int(index), int(index)+1), nil
+	case addToSetPatch:
+		values, err := t.createList(ctx, typ.Elem, patchList(p[op]))
+		if err != nil {
+			return nil, err
+		}
+		list, _ := value.([]any)
+		result := slices.Clone(list)
+		for _, v := range values {
+			if !containsValue(result, v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	case removeValuesPatch:
+		values, err := t.createList(ctx, typ.Elem, patchList(p[op]))
+		if err != nil {
+			return nil, err
+		}
+		list, _ := value.([]any)
+		result := make([]any, 0, len(list))
+		for _, v := range list {
+			if !containsValue(values, v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	case connectPatch, disconnectPatch, createPatch, updatePatch:
+		if typ.Elem == nil {
+			return nil, fmt.Errorf("invalid patch operation %s", op)
+		}
+		def, ok := t.schema.Types[typ.Elem.NamedType]
+		if ok && schema_gen.IsAbstract(def) {
+			return t.patchAbstractList(ctx, def, value, op, p[op])
+		}
+		if !ok || def.Kind != ast.Object {
+			return nil, fmt.Errorf("invalid patch operation %s", op)
+		}
+		return t.patchRelationList(ctx, typ, value, op, p[op])
 	case filterPatch:
+		list, _ := value.([]any)
 		result := make([]any, 0)
-		for _, v := range value.([]any) {
+		for _, v := range list {
 			match, err := t.filterValue(ctx, typ.Elem, v, p[op])
 			if err != nil {
 				return nil, err
@@ -331,34 +705,158 @@ func (t *Transaction) patchValue(ctx context.Context, typ *ast.Type, value any,
 }
 
 func (t *Transaction) patchRelation(ctx context.Context, typ *ast.Type, value any, patch any) (any, error) {
-	if patch == nil {
-		return nil, nil
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid relation patch")
+	}
+	op, ok := relationPatchOp(p)
+	if !ok {
+		return t.patchRelated(ctx, typ, value, p)
+	}
+	if len(p) != 1 {
+		return nil, fmt.Errorf("patch must contain exactly one operation")
 	}
-	err := t.PatchDocument(ctx, typ.NamedType, value.(string), patch.(map[string]any))
+	switch op {
+	case connectPatch:
+		return t.connectRelation(ctx, typ, p[op])
+	case disconnectPatch:
+		if p[op] == true {
+			return nil, nil
+		}
+		return value, nil
+	case createPatch:
+		input, _ := p[op].(map[string]any)
+		return t.CreateDocument(ctx, typ.NamedType, input)
+	default:
+		input, _ := p[op].(map[string]any)
+		return t.patchRelated(ctx, typ, value, input)
+	}
+}
+
+// patchRelated applies the patch to the related document with the given id.
+func (t *Transaction) patchRelated(ctx context.Context, typ *ast.Type, value any, patch map[string]any) (any, error) {
+	id, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("relation is not set")
+	}
+	err := t.PatchDocument(ctx, typ.NamedType, id, patch)
 	if err != nil {
 		return nil, err
 	}
 	return value, nil
 }
 
-func (t *Transaction) filterDocument(ctx context.Context, def *ast.Definition, doc map[string]any, filter any) (bool, error) {
+// connectRelation returns the id after verifying the document exists in the related collection.
+func (t *Transaction) connectRelation(ctx context.Context, typ *ast.Type, value any) (string, error) {
+	id, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid document id %v", value)
+	}
+	exists, err := t.documentExists(ctx, typ.NamedType, id)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("document not found %s", id)
+	}
+	return id, nil
+}
+
+// patchRelationList returns the result of applying the relation patch operation to the list value.
+func (t *Transaction) patchRelationList(ctx context.Context, typ *ast.Type, value any, op string, arg any) (any, error) {
+	list, _ := value.([]any)
+	switch op {
+	case connectPatch:
+		result := slices.Clone(list)
+		for _, v := range patchList(arg) {
+			id, err := t.connectRelation(ctx, typ.Elem, v)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(result, any(id)) {
+				result = append(result, id)
+			}
+		}
+		return result, nil
+	case disconnectPatch:
+		ids := patchList(arg)
+		result := make([]any, 0, len(list))
+		for _, v := range list {
+			if !slices.Contains(ids, v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	case createPatch:
+		values := patchList(arg)
+		result := slices.Clone(list)
+		for i, v := range values {
+			input, ok := v.(map[string]any)
+			if !ok {
+				return nil, prefixPath(newValidationError(TypeErrorCode, "expected an object"), ast.PathIndex(i))
+			}
+			id, err := t.CreateDocument(ctx, typ.Elem.NamedType, input)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, id)
+		}
+		return result, nil
+	default:
+		update, ok := arg.(map[string]any)
+		if !ok {
+			return nil, newValidationError(TypeErrorCode, "expected an object")
+		}
+		patch, _ := update["patch"].(map[string]any)
+		for i, v := range list {
+			id, ok := v.(string)
+			if !ok {
+				return nil, prefixPath(newValidationError(TypeErrorCode, "invalid document id %v", v), ast.PathIndex(i))
+			}
+			match, err := t.FilterDocument(ctx, typ.Elem.NamedType, id, update["filter"])
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+			err = t.PatchDocument(ctx, typ.Elem.NamedType, id, patch)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+}
+
+// relationPatchOp returns the relation patch operation contained in the patch if one exists.
+func relationPatchOp(patch map[string]any) (string, bool) {
+	for _, op := range []string{connectPatch, disconnectPatch, createPatch, updatePatch} {
+		if _, ok := patch[op]; ok {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (t *Transaction) filterDocument(ctx context.Context, def *ast.Definition, id string, doc map[string]any, filter any) (bool, error) {
 	if filter == nil {
 		return true, nil
 	}
 	for key, val := range filter.(map[string]any) {
 		switch key {
 		case andFilter:
-			match, err := t.filterAnd(ctx, def, doc, val)
+			match, err := t.filterAnd(ctx, def, id, doc, val)
 			if err != nil || !match {
 				return false, err
 			}
 		case orFilter:
-			match, err := t.filterOr(ctx, def, doc, val)
+			match, err := t.filterOr(ctx, def, id, doc, val)
 			if err != nil || !match {
 				return false, err
 			}
 		case notFilter:
-			match, err := t.filterDocument(ctx, def, doc, val)
+			match, err := t.filterDocument(ctx, def, id, doc, val)
 			if err != nil || match {
 				return false, err
 			}
@@ -367,7 +865,15 @@ func (t *Transaction) filterDocument(ctx context.Context, def *ast.Definition, d
 			if field == nil {
 				return false, fmt.Errorf("invalid document field %s", key)
 			}
-			match, err := t.filterValue(ctx, field.Type, doc[key], val)
+			value := doc[key]
+			if _, ok := schema_gen.InverseOf(field); ok {
+				inverse, err := t.inverseValue(ctx, def.Name, id, field)
+				if err != nil {
+					return false, err
+				}
+				value = inverse
+			}
+			match, err := t.filterValue(ctx, field.Type, value, val)
 			if err != nil || !match {
 				return false, err
 			}
@@ -380,11 +886,35 @@ func (t *Transaction) filterValue(ctx context.Context, typ *ast.Type, value any,
 	if filter == nil {
 		return true, nil
 	}
-	def := t.repo.schema.Types[typ.NamedType]
-	if def.Kind == ast.Object {
+	if list, ok := filter.([]any); ok {
+		for _, f := range list {
+			match, err := t.filterValue(ctx, typ, value, f)
+			if err != nil || !match {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	def, ok := t.schema.Types[typ.NamedType]
+	if ok && schema_gen.IsEmbedded(def) {
+		return t.filterEmbedded(ctx, def, value, filter)
+	}
+	if ok && schema_gen.IsAbstract(def) {
+		return t.filterAbstract(ctx, def, value, filter)
+	}
+	if ok && def.Kind == ast.Object {
 		return t.filterRelation(ctx, typ, value, filter.(map[string]any))
 	}
 	for key, val := range filter.(map[string]any) {
+		if ok && def.Kind == ast.Scalar {
+			val = coerceFilter(def.Name, key, val)
+		}
+		if value == nil && typ.Elem == nil {
+			if !filterNull(key, val) {
+				return false, nil
+			}
+			continue
+		}
 		switch key {
 		case equalFilter:
 			match, err := filterEqual(value, val)
@@ -397,22 +927,22 @@ func (t *Transaction) filterValue(ctx context.Context, typ *ast.Type, value any,
 				return false, err
 			}
 		case greaterFilter:
-			match, err := filterCompare(value, val)
+			match, err := compareValue(def, value, val)
 			if err != nil || match <= 0 {
 				return false, err
 			}
 		case greaterOrEqualFilter:
-			match, err := filterCompare(value, val)
+			match, err := compareValue(def, value, val)
 			if err != nil || match < 0 {
 				return false, err
 			}
 		case lessFilter:
-			match, err := filterCompare(value, val)
+			match, err := compareValue(def, value, val)
 			if err != nil || match >= 0 {
 				return false, err
 			}
 		case lessOrEqualFilter:
-			match, err := filterCompare(value, val)
+			match, err := compareValue(def, value, val)
 			if err != nil || match > 0 {
 				return false, err
 			}
@@ -452,20 +982,23 @@ func (t *Transaction) filterRelation(ctx context.Context, typ *ast.Type, value a
 	if filter == nil {
 		return true, nil
 	}
+	if value == nil {
+		return false, nil
+	}
 	doc, err := t.ReadDocument(ctx, typ.NamedType, value.(string))
 	if err != nil {
 		return false, err
 	}
-	def := t.repo.schema.Types[typ.NamedType]
-	return t.filterDocument(ctx, def, doc, filter)
+	def := t.schema.Types[typ.NamedType]
+	return t.filterDocument(ctx, def, value.(string), doc, filter)
 }
 
-func (t *Transaction) filterAnd(ctx context.Context, def *ast.Definition, value map[string]any, filter any) (bool, error) {
+func (t *Transaction) filterAnd(ctx context.Context, def *ast.Definition, id string, value map[string]any, filter any) (bool, error) {
 	if filter == nil {
 		return true, nil
 	}
 	for _, v := range filter.([]any) {
-		match, err := t.filterDocument(ctx, def, value, v)
+		match, err := t.filterDocument(ctx, def, id, value, v)
 		if err != nil || !match {
 			return false, err
 		}
@@ -473,12 +1006,12 @@ func (t *Transaction) filterAnd(ctx context.Context, def *ast.Definition, value
 	return true, nil
 }
 
-func (t *Transaction) filterOr(ctx context.Context, def *ast.Definition, value map[string]any, filter any) (bool, error) {
+func (t *Transaction) filterOr(ctx context.Context, def *ast.Definition, id string, value map[string]any, filter any) (bool, error) {
 	if filter == nil {
 		return true, nil
 	}
 	for _, v := range filter.([]any) {
-		match, err := t.filterDocument(ctx, def, value, v)
+		match, err := t.filterDocument(ctx, def, id, value, v)
 		if err != nil || match {
 			return match, err
 		}
@@ -490,7 +1023,8 @@ func (t *Transaction) filterAll(ctx context.Context, typ *ast.Type, value any, f
 	if filter == nil {
 		return true, nil
 	}
-	for _, v := range value.([]any) {
+	list, _ := value.([]any)
+	for _, v := range list {
 		match, err := t.filterValue(ctx, typ.Elem, v, filter)
 		if err != nil || !match {
 			return false, err
@@ -503,7 +1037,8 @@ func (t *Transaction) filterAny(ctx context.Context, typ *ast.Type, value any, f
 	if filter == nil {
 		return true, nil
 	}
-	for _, v := range value.([]any) {
+	list, _ := value.([]any)
+	for _, v := range list {
 		match, err := t.filterValue(ctx, typ.Elem, v, filter)
 		if err != nil || match {
 			return match, err
@@ -512,36 +1047,121 @@ func (t *Transaction) filterAny(ctx context.Context, typ *ast.Type, value any, f
 	return false, nil
 }
 
-func filterIn(value any, filter any) (bool, error) {
-	switch v := value.(type) {
-	case int64:
-		return slices.Contains(filter.([]int64), v), nil
-	case float64:
-		return slices.Contains(filter.([]float64), v), nil
-	case string:
-		return slices.Contains(filter.([]string), v), nil
+// patchList returns the patch values as a list.
+//
+// Single values are coerced into a list as described by the GraphQL input coercion rules.
+func patchList(value any) []any {
+	if list, ok := value.([]any); ok {
+		return list
+	}
+	if value == nil {
+		return nil
+	}
+	return []any{value}
+}
+
+// patchNumber returns the result of applying the numeric patch operation to the value.
+func patchNumber(typ *ast.Type, op string, value any, arg any) (any, error) {
+	if typ.NamedType == "Float" {
+		a, ok := coerceFloat(value)
+		if !ok && value != nil {
+			return nil, fmt.Errorf("invalid kind for %s patch", op)
+		}
+		b, ok := coerceFloat(arg)
+		if !ok {
+			return nil, fmt.Errorf("invalid kind for %s patch", op)
+		}
+		result := applyNumber(op, a, b, value == nil)
+		if math.IsInf(result, 0) {
+			return nil, newValidationError(RangeErrorCode, "result of %s patch is out of range", op)
+		}
+		return result, nil
+	}
+	a, ok := coerceInt(value)
+	if !ok && value != nil {
+		return nil, fmt.Errorf("invalid kind for %s patch", op)
+	}
+	b, ok := coerceInt(arg)
+	if !ok {
+		return nil, fmt.Errorf("invalid kind for %s patch", op)
+	}
+	result := applyNumber(op, a, b, value == nil)
+	if intOverflows(op, a, b, result) {
+		return nil, newValidationError(RangeErrorCode, "result of %s patch is out of range", op)
+	}
+	return result, nil
+}
+
+// intOverflows returns true if the result of the integer patch operation wrapped around.
+func intOverflows(op string, value, arg, result int64) bool {
+	switch op {
+	case incrementPatch:
+		return (arg > 0 && result < value) || (arg < 0 && result > value)
+	case decrementPatch:
+		return (arg > 0 && result > value) || (arg < 0 && result < value)
+	case multiplyPatch:
+		if value == 0 || arg == 0 {
+			return false
+		}
+		return result/arg != value || (arg == -1 && value == math.MinInt64)
 	default:
-		return false, fmt.Errorf("invalid kind for in filter")
+		return false
 	}
 }
 
-func filterCompare(value any, filter any) (int, error) {
-	switch v := value.(type) {
-	case int64:
-		return cmp.Compare(v, filter.(int64)), nil
-	case float64:
-		return cmp.Compare(v, filter.(float64)), nil
-	case string:
-		return cmp.Compare(v, filter.(string)), nil
+// applyNumber returns the result of the numeric patch operation.
+//
+// If empty is true the value is treated as zero.
+func applyNumber[T int64 | float64](op string, value, arg T, empty bool) T {
+	switch op {
+	case incrementPatch:
+		return value + arg
+	case decrementPatch:
+		return value - arg
+	case multiplyPatch:
+		return value * arg
+	case minPatch:
+		if empty {
+			return arg
+		}
+		return min(value, arg)
+	default:
+		if empty {
+			return arg
+		}
+		return max(value, arg)
+	}
+}
+
+// filterNull returns true if a missing value matches the filter operation.
+func filterNull(op string, filter any) bool {
+	switch op {
+	case equalFilter:
+		return filter == nil
+	case notEqualFilter:
+		return filter != nil
+	case notInFilter:
+		return true
 	default:
-		return 0, fmt.Errorf("invalid kind for compare filter")
+		return false
 	}
 }
 
+func filterIn(value any, filter any) (bool, error) {
+	list, _ := filter.([]any)
+	for _, f := range list {
+		match, err := filterEqual(value, f)
+		if err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
+
 func filterEqual(value any, filter any) (bool, error) {
 	switch v := value.(type) {
-	case bool:
-		return v == filter, nil
+	case bool, []any, map[string]any:
+		return valuesEqual(v, filter), nil
 	default:
 		match, err := filterCompare(v, filter)
 		if err != nil {
@@ -2,8 +2,10 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,3 +30,137 @@ func TestTransactionCreateDocument(t *testing.T) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func TestTransactionCreateDocumentWithID(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", id)
+
+	actual, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Bob"}, actual)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"id": "bob", "name": "Alice"})
+	require.Error(t, err)
+}
+
+func TestTransactionCreateDocumentUUIDv7(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User @id(strategy: UUID_V7) { name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	parsed, err := uuid.Parse(id)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), parsed.Version())
+}
+
+func TestTransactionCreateDocumentHashID(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User @id(strategy: HASH, fields: ["email"]) { email: String, name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	txA, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	txB, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	idA, err := txA.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com", "name": "Bob"})
+	require.NoError(t, err)
+
+	idB, err := txB.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com", "name": "Robert"})
+	require.NoError(t, err)
+	assert.Equal(t, idA, idB)
+
+	_, err = txA.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.Error(t, err)
+
+	_, err = txA.CreateDocument(ctx, "User", map[string]any{"id": "bob", "email": "alice@example.com"})
+	require.Error(t, err)
+}
+
+func BenchmarkTransactionCreateDocuments(b *testing.B) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	for _, count := range []int{100, 1000, 10000} {
+		// unbuffered encodes the collection after every write as transactions did before buffering
+		for _, buffered := range []bool{true, false} {
+			name := fmt.Sprintf("documents=%d/buffered=%t", count, buffered)
+			b.Run(name, func(b *testing.B) {
+				if !buffered && count > 1000 {
+					b.Skip("quadratic")
+				}
+				repo, err := InitRepository(ctx, NewMemoryStorage(), schema)
+				require.NoError(b, err)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					tx, err := repo.Transaction(ctx, repo.Head())
+					require.NoError(b, err)
+					for j := 0; j < count; j++ {
+						_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": fmt.Sprintf("user %d", j)})
+						require.NoError(b, err)
+						if !buffered {
+							require.NoError(b, tx.encodeCollections(ctx))
+						}
+					}
+					_, err = tx.Commit(ctx)
+					require.NoError(b, err)
+				}
+				b.ReportMetric(float64(count*b.N)/b.Elapsed().Seconds(), "docs/s")
+			})
+		}
+	}
+}
+
+func TestTransactionReadDocumentsMissing(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	iter, err := tx.DocumentIterator(ctx, "User")
+	require.NoError(t, err)
+
+	_, hash, _, err := iter.Next(ctx)
+	require.NoError(t, err)
+
+	hashes, docs, err := tx.ReadDocuments(ctx, "User", []string{id, "missing"})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, map[string]any{"name": "Bob"}, docs[0])
+	assert.Nil(t, docs[1])
+	assert.Equal(t, hash, hashes[0])
+	assert.Nil(t, hashes[1])
+}
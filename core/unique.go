@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// UniqueErrorCode indicates that a value violates a unique constraint.
+const UniqueErrorCode = "UNIQUE"
+
+// UniqueConflictError is returned when merging introduces documents that violate a unique constraint.
+type UniqueConflictError struct {
+	// Collection is the name of the collection containing the documents.
+	Collection string
+	// Fields are the names of the fields that must be unique.
+	Fields []string
+	// IDs are the ids of the conflicting documents.
+	IDs []string
+}
+
+func (e *UniqueConflictError) Error() string {
+	return fmt.Sprintf("merge conflict: documents %s in %s violate unique constraint on %s",
+		strings.Join(e.IDs, ", "), e.Collection, strings.Join(e.Fields, ", "))
+}
+
+// uniqueKey identifies the values of a unique constraint within a collection.
+type uniqueKey struct {
+	collection string
+	constraint int
+	value      string
+}
+
+// uniqueIndex maps the values of unique constraints to the documents containing them.
+//
+// The index is built from the transaction data root the first time it is
+// used and is maintained as documents are written and removed.
+// Like the reference index it is shared by the hash of its data root.
+type uniqueIndex struct {
+	// keys maps unique values to the id of the document containing them.
+	keys map[uniqueKey]string
+	// docs maps documents to the unique values they contain.
+	docs map[documentKey][]uniqueKey
+}
+
+// uniqueIndex returns the unique constraint index, building it if necessary.
+func (t *Transaction) uniqueIndex(ctx context.Context) (*uniqueIndex, error) {
+	if t.uniques != nil {
+		return t.uniques, nil
+	}
+	key, err := t.indexKey(ctx, "uniques")
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := t.repo.indexes.get(key); ok {
+		t.uniques, t.sharedUniques = cached.(*uniqueIndex), true
+		return t.uniques, nil
+	}
+	collections := make([]string, 0, len(t.data.Collections))
+	for collection := range t.data.Collections {
+		collections = append(collections, collection)
+	}
+	index, err := t.buildUniqueIndex(ctx, collections)
+	if err != nil {
+		return nil, err
+	}
+	t.repo.indexes.add(key, index, 1)
+	t.uniques, t.sharedUniques = index, true
+	return index, nil
+}
+
+// buildUniqueIndex returns a unique constraint index of the given collections.
+//
+// A UniqueConflictError is returned if any documents violate a unique constraint.
+func (t *Transaction) buildUniqueIndex(ctx context.Context, collections []string) (*uniqueIndex, error) {
+	index := &uniqueIndex{
+		keys: make(map[uniqueKey]string),
+		docs: make(map[documentKey][]uniqueKey),
+	}
+	for _, collection := range collections {
+		def := t.schema.Types[collection]
+		constraints := schema_gen.UniqueConstraints(def)
+		if len(constraints) == 0 {
+			continue
+		}
+		iter, err := t.DocumentIterator(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		for !iter.Done() {
+			id, _, doc, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			keys, err := uniqueKeys(def, doc)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range keys {
+				if other, ok := index.keys[key]; ok {
+					return nil, &UniqueConflictError{
+						Collection: collection,
+						Fields:     constraints[key.constraint],
+						IDs:        []string{other, id},
+					}
+				}
+			}
+			index.indexDocument(collection, id, keys)
+		}
+	}
+	return index, nil
+}
+
+// checkUnique ensures the document does not violate any unique constraints of the collection.
+func (t *Transaction) checkUnique(ctx context.Context, def *ast.Definition, id string, doc object.Document) error {
+	constraints := schema_gen.UniqueConstraints(def)
+	if len(constraints) == 0 {
+		return nil
+	}
+	index, err := t.uniqueIndex(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := uniqueKeys(def, doc)
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	for _, key := range keys {
+		other, ok := index.keys[key]
+		if !ok || other == id {
+			continue
+		}
+		fields := constraints[key.constraint]
+		errs = append(errs, &ValidationError{
+			Path:    ast.Path{ast.PathName(fields[0])},
+			Code:    UniqueErrorCode,
+			Message: fmt.Sprintf("value of %s must be unique", strings.Join(fields, ", ")),
+		})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// indexDocument adds the unique values of the document to the index.
+func (index *uniqueIndex) indexDocument(collection, id string, keys []uniqueKey) {
+	for _, key := range keys {
+		index.keys[key] = id
+	}
+	if len(keys) > 0 {
+		index.docs[documentKey{collection, id}] = keys
+	}
+}
+
+// clone returns a copy of the index that can be modified independently.
+func (index *uniqueIndex) clone() *uniqueIndex {
+	return &uniqueIndex{
+		keys: maps.Clone(index.keys),
+		docs: maps.Clone(index.docs),
+	}
+}
+
+// unindexDocument removes the unique values of the document from the index.
+func (index *uniqueIndex) unindexDocument(collection, id string) {
+	source := documentKey{collection, id}
+	for _, key := range index.docs[source] {
+		if index.keys[key] == id {
+			delete(index.keys, key)
+		}
+	}
+	delete(index.docs, source)
+}
+
+// uniqueKeys returns the keys of the unique constraints the document contains values for.
+//
+// Constraints are ignored when any of their fields do not have a value.
+func uniqueKeys(def *ast.Definition, doc object.Document) ([]uniqueKey, error) {
+	var keys []uniqueKey
+	for i, fields := range schema_gen.UniqueConstraints(def) {
+		values := make([]any, len(fields))
+		for j, f := range fields {
+			values[j] = doc[f]
+		}
+		if slices.ContainsFunc(values, func(v any) bool { return v == nil }) {
+			continue
+		}
+		hash, err := hashObject(values)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, uniqueKey{collection: def.Name, constraint: i, value: hash.String()})
+	}
+	return keys, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueField(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { email: String @unique }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.NoError(t, err)
+
+	var errs ValidationErrors
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, UniqueErrorCode, errs[0].Code)
+	assert.Equal(t, "email", errs[0].Path.String())
+
+	// documents without a value are not constrained
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{})
+	require.NoError(t, err)
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{})
+	require.NoError(t, err)
+
+	// patching a document to its own value is allowed
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"email": map[string]any{"set": "bob@example.com"}})
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"email": map[string]any{"set": "alice@example.com"}})
+	require.NoError(t, err)
+
+	// the previous value is released after a patch
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"email": map[string]any{"set": "bob@example.com"}})
+	require.ErrorAs(t, err, &errs)
+}
+
+func TestUniqueComposite(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User @unique(fields: ["first", "last"]) { first: String last: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"first": "Bob", "last": "Smith"})
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"first": "Bob", "last": "Jones"})
+	require.NoError(t, err)
+
+	var errs ValidationErrors
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"first": "Bob", "last": "Smith"})
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, UniqueErrorCode, errs[0].Code)
+
+	err = tx.DeleteDocument(ctx, "User", id)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"first": "Bob", "last": "Smith"})
+	require.NoError(t, err)
+}
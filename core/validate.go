@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// RequiredErrorCode indicates that a non-null field does not have a value.
+	RequiredErrorCode = "REQUIRED"
+	// TypeErrorCode indicates that a value does not match the type of its field.
+	TypeErrorCode = "INVALID_TYPE"
+	// RelationErrorCode indicates that a relation references a document that does not exist.
+	RelationErrorCode = "INVALID_RELATION"
+)
+
+// ValidationError describes a document value that does not conform to the schema.
+type ValidationError struct {
+	// Path is the location of the invalid value within the document.
+	Path ast.Path
+	// Code is a machine readable code describing the error.
+	Code string
+	// Message is a human readable description of the error.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path.String(), e.Message)
+}
+
+// ValidationErrors is a list of validation errors.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// newValidationError returns a new validation error with the given code and message.
+func newValidationError(code string, format string, args ...any) *ValidationError {
+	return &ValidationError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// prefixPath returns the error with the given path elements prepended to any validation error paths.
+func prefixPath(err error, prefix ...ast.PathElement) error {
+	switch t := err.(type) {
+	case *ValidationError:
+		return &ValidationError{
+			Path:    append(append(ast.Path{}, prefix...), t.Path...),
+			Code:    t.Code,
+			Message: t.Message,
+		}
+	case ValidationErrors:
+		errs := make(ValidationErrors, len(t))
+		for i, e := range t {
+			errs[i] = prefixPath(e, prefix...).(*ValidationError)
+		}
+		return errs
+	default:
+		return err
+	}
+}
+
+// validateDocument ensures the document conforms to the given definition.
+func (t *Transaction) validateDocument(ctx context.Context, def *ast.Definition, doc object.Document) error {
+	errs := t.validateFields(ctx, nil, def, doc)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateFields ensures the field values conform to the given definition.
+func (t *Transaction) validateFields(ctx context.Context, path ast.Path, def *ast.Definition, doc map[string]any) ValidationErrors {
+	var errs ValidationErrors
+	for key := range doc {
+		if def.Fields.ForName(key) == nil {
+			fieldPath := append(append(ast.Path{}, path...), ast.PathName(key))
+			errs = append(errs, &ValidationError{Path: fieldPath, Code: TypeErrorCode, Message: "unknown field"})
+		}
+	}
+	for _, field := range def.Fields {
+		if schema_gen.IsSystemField(field.Name) {
+			continue // ignore system fields
+		}
+		if _, ok := schema_gen.InverseOf(field); ok {
+			continue // ignore computed fields
+		}
+		fieldPath := append(append(ast.Path{}, path...), ast.PathName(field.Name))
+		fieldErrs := t.validateValue(ctx, fieldPath, field.Type, doc[field.Name])
+		if len(fieldErrs) == 0 {
+			fieldErrs = validateConstraints(fieldPath, field, doc[field.Name])
+		}
+		errs = append(errs, fieldErrs...)
+	}
+	return errs
+}
+
+// validateValue ensures the value conforms to the given type.
+func (t *Transaction) validateValue(ctx context.Context, path ast.Path, typ *ast.Type, value any) ValidationErrors {
+	if value == nil {
+		if !typ.NonNull {
+			return nil
+		}
+		return ValidationErrors{{Path: path, Code: RequiredErrorCode, Message: "value is required"}}
+	}
+	if typ.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: "expected a list"}}
+		}
+		var errs ValidationErrors
+		for i, v := range list {
+			elemPath := append(append(ast.Path{}, path...), ast.PathIndex(i))
+			errs = append(errs, t.validateValue(ctx, elemPath, typ.Elem, v)...)
+		}
+		return errs
+	}
+	def, ok := t.schema.Types[typ.NamedType]
+	if !ok {
+		return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: fmt.Sprintf("unknown type %s", typ.NamedType)}}
+	}
+	if schema_gen.IsEmbedded(def) {
+		return t.validateEmbedded(ctx, path, def, value)
+	}
+	if schema_gen.IsAbstract(def) {
+		return t.validateAbstract(ctx, path, def, value)
+	}
+	if def.Kind == ast.Object {
+		return t.validateRelation(ctx, path, typ, value)
+	}
+	if !validScalar(def, value) {
+		return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: fmt.Sprintf("expected a value of type %s", typ.NamedType)}}
+	}
+	return nil
+}
+
+// validateRelation ensures the relation value references an existing document.
+func (t *Transaction) validateRelation(ctx context.Context, path ast.Path, typ *ast.Type, value any) ValidationErrors {
+	id, ok := value.(string)
+	if !ok {
+		return ValidationErrors{{Path: path, Code: TypeErrorCode, Message: "expected a document id"}}
+	}
+	exists, err := t.documentExists(ctx, typ.NamedType, id)
+	if err != nil || !exists {
+		return ValidationErrors{{Path: path, Code: RelationErrorCode, Message: fmt.Sprintf("document not found %s", id)}}
+	}
+	return nil
+}
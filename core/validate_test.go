@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequiredField(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String! }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{})
+	require.Error(t, err)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, RequiredErrorCode, errs[0].Code)
+	assert.Equal(t, "name", errs[0].Path.String())
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+
+	err = tx.PatchDocument(ctx, "User", id, map[string]any{"name": map[string]any{"set": nil}})
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, RequiredErrorCode, errs[0].Code)
+}
+
+func TestValidateInvalidType(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User {
+		name: String
+		age: Int
+		tags: [String!]
+		friends: [User]
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	var verr *ValidationError
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"name": 5})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, TypeErrorCode, verr.Code)
+	assert.Equal(t, "name", verr.Path.String())
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"tags": []any{"a", 1}})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "tags[1]", verr.Path.String())
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"friends": []any{"bob"}})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "friends[0]", verr.Path.String())
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{"email": "bob@example.com"})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "email", verr.Path.String())
+
+	id, err := tx.CreateDocument(ctx, "User", map[string]any{"age": 30})
+	require.NoError(t, err)
+
+	doc, err := tx.ReadDocument(ctx, "User", id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), doc["age"])
+}
+
+func TestValidatePatchInvalidType(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Book { title: String }`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	id, err := tx.CreateDocument(ctx, "Book", map[string]any{"title": "Dune"})
+	require.NoError(t, err)
+
+	var verr *ValidationError
+	err = tx.PatchDocument(ctx, "Book", id, map[string]any{"title": map[string]any{"set": []any{"Dune"}}})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, TypeErrorCode, verr.Code)
+	assert.Equal(t, "title", verr.Path.String())
+
+	err = tx.PatchDocument(ctx, "Book", id, map[string]any{"title": "Dune"})
+	require.Error(t, err)
+}
+
+func TestValidateConstraints(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User {
+		name: String @length(min: 2, max: 5) @pattern(regex: "^[a-z]+$")
+		age: Int @range(min: 0, max: 150)
+		email: String @format(kind: EMAIL)
+		website: String @format(kind: URL)
+		tags: [String] @length(max: 2) @format(kind: UUID)
+	}`
+	storage := NewMemoryStorage()
+
+	repo, err := InitRepository(ctx, storage, schema)
+	require.NoError(t, err)
+
+	tx, err := repo.Transaction(ctx, repo.head)
+	require.NoError(t, err)
+
+	invalid := []struct {
+		doc  map[string]any
+		code string
+		path string
+	}{
+		{map[string]any{"name": "b"}, LengthErrorCode, "name"},
+		{map[string]any{"name": "bobbert"}, LengthErrorCode, "name"},
+		{map[string]any{"name": "Bob"}, PatternErrorCode, "name"},
+		{map[string]any{"age": -1}, RangeErrorCode, "age"},
+		{map[string]any{"email": "bob"}, FormatErrorCode, "email"},
+		{map[string]any{"website": "example.com"}, FormatErrorCode, "website"},
+		{map[string]any{"tags": []any{"a", "b", "c"}}, LengthErrorCode, "tags"},
+		{map[string]any{"tags": []any{"a"}}, FormatErrorCode, "tags[0]"},
+	}
+	for _, test := range invalid {
+		var errs ValidationErrors
+		_, err = tx.CreateDocument(ctx, "User", test.doc)
+		require.ErrorAs(t, err, &errs)
+		assert.Equal(t, test.code, errs[0].Code)
+		assert.Equal(t, test.path, errs[0].Path.String())
+	}
+
+	_, err = tx.CreateDocument(ctx, "User", map[string]any{
+		"name":    "bob",
+		"age":     30,
+		"email":   "bob@example.com",
+		"website": "https://example.com",
+		"tags":    []any{"0191e1a8-d4c6-7d51-9c5e-1b6f4f5d2e3a"},
+	})
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package graphql
 
 import (
 	"context"
+	"errors"
 
 	"github.com/rodent-software/capy/core"
 
@@ -16,29 +17,49 @@ type QueryParams struct {
 	Variables     map[string]any `json:"variables"`
 }
 
+// maxMutationAttempts is the number of times a mutation is executed before a conflict is returned.
+const maxMutationAttempts = 3
+
 // Execute runs the query and returns a node containing the result of the query operation.
+//
+// Mutations are executed on a snapshot of the repo head. If the head moved and the
+// changes conflict, the mutation is executed again on the new head.
 func Execute(ctx context.Context, repo *core.Repository, params QueryParams) QueryResponse {
-	exe, errs := NewRequest(ctx, repo, params)
-	if errs != nil {
-		return NewQueryResponse(nil, errs)
+	for attempt := 1; ; attempt++ {
+		data, err := execute(ctx, repo, params)
+		if core.IsConflict(err) && attempt < maxMutationAttempts {
+			continue
+		}
+		return NewQueryResponse(data, err)
+	}
+}
+
+// execute runs the query once and commits the changes of mutations.
+func execute(ctx context.Context, repo *core.Repository, params QueryParams) (any, error) {
+	exe, err := NewRequest(ctx, repo, params)
+	if err != nil {
+		return nil, err
 	}
 	data, err := exe.Execute(ctx)
 	if err != nil {
-		return NewQueryResponse(nil, err)
+		exe.tx.Rollback()
+		return nil, err
 	}
 	if exe.operation.Operation != ast.Mutation {
-		return NewQueryResponse(data, nil)
+		// the read commit no longer needs to be kept by GC
+		exe.tx.Rollback()
+		return data, nil
 	}
-	// commit the transaction
-	hash, err := exe.tx.Commit(ctx)
+	head, err := repo.CommitTransaction(ctx, exe.tx, exe.commitInfo())
 	if err != nil {
-		return NewQueryResponse(nil, err)
+		exe.tx.Rollback()
+		return nil, err
 	}
-	err = repo.Merge(ctx, hash)
+	err = exe.resolveHeadCommits(ctx, head)
 	if err != nil {
-		return NewQueryResponse(nil, err)
+		return nil, err
 	}
-	return NewQueryResponse(data, nil)
+	return data, nil
 }
 
 // QueryResponse contains all of the fields for a response.
@@ -53,6 +74,14 @@ func NewQueryResponse(data any, err error) QueryResponse {
 	response := QueryResponse{
 		Data: data,
 	}
+	var validationErrs core.ValidationErrors
+	var validationErr *core.ValidationError
+	switch {
+	case errors.As(err, &validationErrs):
+		err = validationErrorList(validationErrs)
+	case errors.As(err, &validationErr):
+		err = validationErrorList(core.ValidationErrors{validationErr})
+	}
 	switch t := err.(type) {
 	case nil:
 		response.Errors = nil
@@ -66,6 +95,21 @@ func NewQueryResponse(data any, err error) QueryResponse {
 	return response
 }
 
+// validationErrorList returns a list of GraphQL errors describing the validation errors.
+func validationErrorList(errs core.ValidationErrors) gqlerror.List {
+	list := make(gqlerror.List, len(errs))
+	for i, e := range errs {
+		list[i] = &gqlerror.Error{
+			Message: e.Error(),
+			Extensions: map[string]any{
+				"code":  e.Code,
+				"field": e.Path.String(),
+			},
+		}
+	}
+	return list
+}
+
 // ToMap converts the query response to a standard go map.
 //
 // This is used to convert values in environments such as WASM.
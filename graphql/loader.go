@@ -0,0 +1,164 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/graphql/schema_gen"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// documentKey identifies a document in a collection.
+type documentKey struct {
+	collection string
+	id         string
+}
+
+// loadedDocument is a document read by a documentLoader.
+type loadedDocument struct {
+	hash string
+	doc  map[string]any
+}
+
+// documentLoader batches and memoizes the documents read by a request.
+//
+// Loaded documents are shared by all fields that reference them and must not be modified.
+// Documents that do not exist are loaded as nil.
+type documentLoader struct {
+	tx   *core.Transaction
+	docs map[documentKey]loadedDocument
+}
+
+// newDocumentLoader returns a loader that reads documents from the transaction.
+func newDocumentLoader(tx *core.Transaction) *documentLoader {
+	return &documentLoader{
+		tx:   tx,
+		docs: make(map[documentKey]loadedDocument),
+	}
+}
+
+// load returns the hash and document from the given collection with the matching id.
+//
+// The document is nil if it does not exist.
+func (l *documentLoader) load(ctx context.Context, collection, id string) (string, map[string]any, error) {
+	err := l.loadMany(ctx, collection, []string{id})
+	if err != nil {
+		return "", nil, err
+	}
+	loaded := l.docs[documentKey{collection, id}]
+	return loaded.hash, loaded.doc, nil
+}
+
+// loadMany reads all documents from the given collection with the matching ids that have not been loaded.
+func (l *documentLoader) loadMany(ctx context.Context, collection string, ids []string) error {
+	missing := make([]string, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := l.docs[documentKey{collection, id}]; ok {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	hashes, docs, err := l.tx.ReadDocuments(ctx, collection, missing)
+	if err != nil {
+		return err
+	}
+	for i, id := range missing {
+		l.docs[documentKey{collection, id}] = loadedDocument{hash: hashes[i].String(), doc: docs[i]}
+	}
+	return nil
+}
+
+// clear removes all loaded documents so that changes made by the transaction are read.
+func (l *documentLoader) clear() {
+	clear(l.docs)
+}
+
+// loadRelations loads the documents referenced by the selected relation fields of all documents at once.
+func (e *Request) loadRelations(ctx context.Context, collection string, docs []map[string]any, field graphql.CollectedField) error {
+	def, ok := e.schema.Types[collection]
+	if !ok {
+		return nil
+	}
+	for _, f := range e.collectFields(field.SelectionSet, e.satisfies(collection)...) {
+		fd := def.Fields.ForName(f.Name)
+		if fd == nil || len(f.SelectionSet) == 0 {
+			continue
+		}
+		if _, ok := schema_gen.InverseOf(fd); ok {
+			continue // inverse relations are not stored in the document
+		}
+		values := make([]any, 0, len(docs))
+		for _, doc := range docs {
+			values = append(values, doc[f.Name])
+		}
+		err := e.loadValues(ctx, fd.Type, values, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadValues loads the documents referenced by the relation values of the given type grouped by collection.
+//
+// Relations selected by the field are loaded from the referenced documents in the same way.
+func (e *Request) loadValues(ctx context.Context, typ *ast.Type, values []any, field graphql.CollectedField) error {
+	named := typ
+	for named.Elem != nil {
+		named = named.Elem
+	}
+	def := e.schema.Types[named.NamedType]
+	if def == nil || schema_gen.IsEmbedded(def) || (def.Kind != ast.Object && !schema_gen.IsAbstract(def)) {
+		return nil
+	}
+	ids := make(map[string][]string)
+	var collect func(value any)
+	collect = func(value any) {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				collect(item)
+			}
+		case nil:
+		default:
+			if schema_gen.IsAbstract(def) {
+				collection, id, ok := core.ParseReference(v)
+				if ok {
+					ids[collection] = append(ids[collection], id)
+				}
+				return
+			}
+			if id, ok := v.(string); ok {
+				ids[def.Name] = append(ids[def.Name], id)
+			}
+		}
+	}
+	for _, value := range values {
+		collect(value)
+	}
+	for collection, ids := range ids {
+		err := e.loader.loadMany(ctx, collection, ids)
+		if err != nil {
+			return err
+		}
+		docs := make([]map[string]any, 0, len(ids))
+		for _, id := range ids {
+			docs = append(docs, e.loader.docs[documentKey{collection, id}].doc)
+		}
+		err = e.loadRelations(ctx, collection, docs, field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,9 +2,12 @@ package graphql
 
 import (
 	"context"
+	"maps"
 	"slices"
 	"strings"
 
+	"github.com/rodent-software/capy/object"
+
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
@@ -14,6 +17,8 @@ func (e *Request) executeMutation(ctx context.Context, set ast.SelectionSet) (an
 	fields := e.collectFields(set, "Mutation")
 	result := make(map[string]any, len(fields))
 	for _, field := range fields {
+		// documents loaded by earlier fields may have been changed
+		e.loader.clear()
 		switch {
 		case strings.HasPrefix(field.Name, createOperationPrefix):
 			collection := strings.TrimPrefix(field.Name, createOperationPrefix)
@@ -39,6 +44,20 @@ func (e *Request) executeMutation(ctx context.Context, set ast.SelectionSet) (an
 			}
 			result[field.Alias] = res
 
+		case field.Name == revertFieldName:
+			res, err := e.revertMutation(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
+		case field.Name == cherryPickFieldName:
+			res, err := e.cherryPickMutation(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
 		default:
 			return nil, gqlerror.Errorf("unsupported mutation %s", field.Name)
 		}
@@ -46,6 +65,51 @@ func (e *Request) executeMutation(ctx context.Context, set ast.SelectionSet) (an
 	return result, nil
 }
 
+func (e *Request) revertMutation(ctx context.Context, field graphql.CollectedField) (any, error) {
+	args := field.ArgumentMap(e.params.Variables)
+	hash, err := parseHash(args["hash"].(string))
+	if err != nil {
+		return nil, err
+	}
+	err = e.tx.Revert(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return e.headCommitResult(field), nil
+}
+
+func (e *Request) cherryPickMutation(ctx context.Context, field graphql.CollectedField) (any, error) {
+	args := field.ArgumentMap(e.params.Variables)
+	hash, err := parseHash(args["hash"].(string))
+	if err != nil {
+		return nil, err
+	}
+	err = e.tx.CherryPick(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return e.headCommitResult(field), nil
+}
+
+// headCommitResult returns a result that is set to the selected fields of the new head once the request is committed.
+func (e *Request) headCommitResult(field graphql.CollectedField) map[string]any {
+	result := make(map[string]any)
+	e.headCommits = append(e.headCommits, headCommit{result: result, field: field})
+	return result
+}
+
+// resolveHeadCommits sets the results of the mutations that return the new head commit.
+func (e *Request) resolveHeadCommits(ctx context.Context, head object.Hash) error {
+	for _, c := range e.headCommits {
+		res, err := e.queryCommit(ctx, head, nil, c.field)
+		if err != nil {
+			return err
+		}
+		maps.Copy(c.result, res.(map[string]any))
+	}
+	return nil
+}
+
 func (e *Request) createMutation(ctx context.Context, field graphql.CollectedField, collection string) (any, error) {
 	args := field.ArgumentMap(e.params.Variables)
 	data, _ := args["data"].(map[string]any)
@@ -74,9 +138,12 @@ func (e *Request) updateMutation(ctx context.Context, field graphql.CollectedFie
 		ctx = context.WithValue(ctx, idContextKey, id)
 		ctx = context.WithValue(ctx, hashContextKey, hash.String())
 		match, err := e.tx.FilterDocument(ctx, collection, id, filter)
-		if err != nil || !match {
+		if err != nil {
 			return nil, err
 		}
+		if !match {
+			continue
+		}
 		err = e.tx.PatchDocument(ctx, collection, id, patch)
 		if err != nil {
 			return nil, err
@@ -124,9 +191,12 @@ func (e *Request) deleteMutation(ctx context.Context, field graphql.CollectedFie
 		ctx = context.WithValue(ctx, idContextKey, id)
 		ctx = context.WithValue(ctx, hashContextKey, hash.String())
 		match, err := e.tx.FilterDocument(ctx, collection, id, filter)
-		if err != nil || !match {
+		if err != nil {
 			return nil, err
 		}
+		if !match {
+			continue
+		}
 		data, err := e.queryDocument(ctx, collection, doc, field)
 		if err != nil {
 			return nil, err
@@ -135,6 +205,7 @@ func (e *Request) deleteMutation(ctx context.Context, field graphql.CollectedFie
 		if err != nil {
 			return nil, err
 		}
+		e.loader.clear()
 		result = append(result, data)
 	}
 	return result, nil
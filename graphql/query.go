@@ -2,8 +2,17 @@ package graphql
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/graphql/schema_gen"
+	"github.com/rodent-software/capy/object"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/vektah/gqlparser/v2/ast"
@@ -11,9 +20,19 @@ import (
 )
 
 const (
-	idFieldName      = "id"
-	hashFieldName    = "hash"
-	commitsFieldName = "commits"
+	idFieldName         = "id"
+	hashFieldName       = "hash"
+	commitsFieldName    = "commits"
+	parentsFieldName    = "parents"
+	dataRootFieldName   = "dataRoot"
+	timeFieldName       = "time"
+	authorFieldName     = "author"
+	messageFieldName    = "message"
+	commitFieldName     = "commit"
+	documentFieldName   = "document"
+	fieldFieldName      = "field"
+	revertFieldName     = "revert"
+	cherryPickFieldName = "cherryPick"
 )
 
 func (e *Request) executeQuery(ctx context.Context, set ast.SelectionSet) (any, error) {
@@ -45,6 +64,45 @@ func (e *Request) executeQuery(ctx context.Context, set ast.SelectionSet) (any,
 			}
 			result[field.Alias] = res
 
+		case field.Name == "commit":
+			res, err := e.commitQuery(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
+		case field.Name == "mergeBase":
+			res, err := e.mergeBaseQuery(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
+		case field.Name == "isAncestor":
+			res, err := e.isAncestorQuery(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
+		case strings.HasPrefix(field.Name, historyOperationPrefix):
+			collection := strings.TrimPrefix(field.Name, historyOperationPrefix)
+			args := field.ArgumentMap(e.params.Variables)
+			res, err := e.queryHistory(ctx, collection, args["id"].(string), field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
+		case strings.HasPrefix(field.Name, blameOperationPrefix):
+			collection := strings.TrimPrefix(field.Name, blameOperationPrefix)
+			args := field.ArgumentMap(e.params.Variables)
+			res, err := e.queryBlame(ctx, collection, args["id"].(string), field)
+			if err != nil {
+				return nil, err
+			}
+			result[field.Alias] = res
+
 		case strings.HasPrefix(field.Name, findOperationPrefix):
 			collection := strings.TrimPrefix(field.Name, findOperationPrefix)
 			args := field.ArgumentMap(e.params.Variables)
@@ -56,6 +114,14 @@ func (e *Request) executeQuery(ctx context.Context, set ast.SelectionSet) (any,
 
 		case strings.HasPrefix(field.Name, listOperationPrefix):
 			collection := strings.TrimPrefix(field.Name, listOperationPrefix)
+			if def := e.schema.Types[collection]; schema_gen.IsAbstract(def) {
+				res, err := e.listAbstractQuery(ctx, field, def)
+				if err != nil {
+					return nil, err
+				}
+				result[field.Alias] = res
+				continue
+			}
 			res, err := e.listQuery(ctx, field, collection)
 			if err != nil {
 				return nil, err
@@ -69,22 +135,210 @@ func (e *Request) executeQuery(ctx context.Context, set ast.SelectionSet) (any,
 	return result, nil
 }
 
+// commitsQuery returns the ancestors of the revision in the order they are visited.
+//
+// The first argument limits the number of results, after skips commits up to
+// and including the given commit, and until excludes a commit and its ancestors.
 func (e *Request) commitsQuery(ctx context.Context, field graphql.CollectedField) (any, error) {
-	fields := e.collectFields(field.SelectionSet, commitsFieldName)
+	args := field.ArgumentMap(e.params.Variables)
+	first, hasFirst := args["first"].(int64)
+	after, hasAfter := args["after"].(string)
+	excluded := make(map[string]struct{})
+	if until, ok := args["until"].(string); ok {
+		hash, err := parseHash(until)
+		if err != nil {
+			return nil, err
+		}
+		iter := e.tx.Repository().CommitIterator(hash)
+		for !iter.Done() {
+			l, _, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			excluded[l.String()] = struct{}{}
+		}
+	}
 	result := make([]any, 0)
 	iter := e.tx.CommitIterator()
-	for !iter.Done() {
-		l, _, err := iter.Next(ctx)
+	for !iter.Done() && (!hasFirst || int64(len(result)) < first) {
+		l, commit, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := excluded[l.String()]; ok {
+			iter.Skip()
+			continue
+		}
+		if hasAfter {
+			hasAfter = l.String() != after
+			continue
+		}
+		res, err := e.queryCommit(ctx, l, commit, field)
 		if err != nil {
 			return nil, err
 		}
+		result = append(result, res)
+	}
+	return result, nil
+}
+
+func (e *Request) commitQuery(ctx context.Context, field graphql.CollectedField) (any, error) {
+	args := field.ArgumentMap(e.params.Variables)
+	hash, err := parseHash(args["hash"].(string))
+	if err != nil {
+		return nil, err
+	}
+	commit, err := e.tx.Repository().Commit(ctx, hash)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.queryCommit(ctx, hash, commit, field)
+}
+
+func (e *Request) mergeBaseQuery(ctx context.Context, field graphql.CollectedField) (any, error) {
+	a, b, err := e.commitPair(field)
+	if err != nil {
+		return nil, err
+	}
+	base, err := e.tx.Repository().MergeBase(ctx, a, b)
+	if err != nil || base == nil {
+		return nil, err
+	}
+	return e.queryCommit(ctx, base, nil, field)
+}
+
+func (e *Request) isAncestorQuery(ctx context.Context, field graphql.CollectedField) (any, error) {
+	a, b, err := e.commitPair(field)
+	if err != nil {
+		return nil, err
+	}
+	return e.tx.Repository().IsAncestor(ctx, a, b)
+}
+
+// commitPair returns the commit hashes of the a and b arguments.
+func (e *Request) commitPair(field graphql.CollectedField) (object.Hash, object.Hash, error) {
+	args := field.ArgumentMap(e.params.Variables)
+	a, err := parseHash(args["a"].(string))
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := parseHash(args["b"].(string))
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+// queryCommit returns the selected fields of the commit with the given hash.
+//
+// The commit is loaded from the repo if it is nil and any fields other than the hash are selected.
+func (e *Request) queryCommit(ctx context.Context, hash object.Hash, commit *object.Commit, field graphql.CollectedField) (any, error) {
+	fields := e.collectFields(field.SelectionSet, "Commit")
+	result := make(map[string]any)
+	for _, f := range fields {
+		switch f.Name {
+		case "__typename":
+			result[f.Alias] = "Commit"
+			continue
+		case hashFieldName:
+			result[f.Alias] = hash.String()
+			continue
+		}
+		if commit == nil {
+			var err error
+			commit, err = e.tx.Repository().Commit(ctx, hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch f.Name {
+		case parentsFieldName:
+			parents := make([]any, len(commit.Parents))
+			for i, p := range commit.Parents {
+				res, err := e.queryCommit(ctx, p, nil, f)
+				if err != nil {
+					return nil, err
+				}
+				parents[i] = res
+			}
+			result[f.Alias] = parents
+		case dataRootFieldName:
+			result[f.Alias] = commit.DataRoot.String()
+		case timeFieldName:
+			result[f.Alias] = nil
+			if !commit.Time.IsZero() {
+				result[f.Alias] = commit.Time.Format(time.RFC3339Nano)
+			}
+		case authorFieldName:
+			result[f.Alias] = optionalString(commit.Author)
+		case messageFieldName:
+			result[f.Alias] = optionalString(commit.Message)
+		default:
+			return nil, fmt.Errorf("unknown commit field: %s", f.Name)
+		}
+	}
+	return result, nil
+}
+
+// optionalString returns nil if the value is empty.
+func optionalString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// parseHash returns the hash encoded in the hex string.
+func parseHash(value string) (object.Hash, error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit hash %s", value)
+	}
+	return b, nil
+}
+
+// queryHistory returns the versions of the document in the given collection with the matching id.
+func (e *Request) queryHistory(ctx context.Context, collection, id string, field graphql.CollectedField) (any, error) {
+	versions, err := e.tx.DocumentHistory(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	typename := collection + "Version"
+	fields := e.collectFields(field.SelectionSet, typename)
+	result := make([]any, 0, len(versions))
+	for _, version := range versions {
 		res := make(map[string]any)
 		for _, f := range fields {
 			switch f.Name {
+			case "__typename":
+				res[f.Alias] = typename
+			case commitFieldName:
+				commit, err := e.queryCommit(ctx, version.Commit, nil, f)
+				if err != nil {
+					return nil, err
+				}
+				res[f.Alias] = commit
 			case hashFieldName:
-				res[f.Alias] = l.String()
+				res[f.Alias] = nil
+				if version.Hash != nil {
+					res[f.Alias] = version.Hash.String()
+				}
+			case documentFieldName:
+				res[f.Alias] = nil
+				if version.Document == nil {
+					continue
+				}
+				ctx := context.WithValue(ctx, hashContextKey, version.Hash.String())
+				doc, err := e.queryDocument(ctx, collection, version.Document, f)
+				if err != nil {
+					return nil, err
+				}
+				res[f.Alias] = doc
 			default:
-				return nil, fmt.Errorf("unknown commit field: %s", f.Name)
+				return nil, fmt.Errorf("unknown version field: %s", f.Name)
 			}
 		}
 		result = append(result, res)
@@ -92,34 +346,102 @@ func (e *Request) commitsQuery(ctx context.Context, field graphql.CollectedField
 	return result, nil
 }
 
-func (e *Request) findQuery(ctx context.Context, field graphql.CollectedField, collection string, id string) (any, error) {
-	doc, err := e.tx.ReadDocument(ctx, collection, id)
+// queryBlame returns the commits that last set each field of the document ordered by field name.
+func (e *Request) queryBlame(ctx context.Context, collection, id string, field graphql.CollectedField) (any, error) {
+	blame, err := e.tx.DocumentBlame(ctx, collection, id)
 	if err != nil {
 		return nil, err
 	}
+	names := slices.Sorted(maps.Keys(blame))
+	fields := e.collectFields(field.SelectionSet, "FieldBlame")
+	result := make([]any, 0, len(names))
+	for _, name := range names {
+		res := make(map[string]any)
+		for _, f := range fields {
+			switch f.Name {
+			case "__typename":
+				res[f.Alias] = "FieldBlame"
+			case fieldFieldName:
+				res[f.Alias] = name
+			case commitFieldName:
+				commit, err := e.queryCommit(ctx, blame[name], nil, f)
+				if err != nil {
+					return nil, err
+				}
+				res[f.Alias] = commit
+			default:
+				return nil, fmt.Errorf("unknown blame field: %s", f.Name)
+			}
+		}
+		result = append(result, res)
+	}
+	return result, nil
+}
+
+func (e *Request) findQuery(ctx context.Context, field graphql.CollectedField, collection string, id string) (any, error) {
+	hash, doc, err := e.loader.load(ctx, collection, id)
+	if err != nil || doc == nil {
+		return nil, err
+	}
 	ctx = context.WithValue(ctx, idContextKey, id)
+	ctx = context.WithValue(ctx, hashContextKey, hash)
 	return e.queryDocument(ctx, collection, doc, field)
 }
 
+// listEntry is a document matched by a list query.
+type listEntry struct {
+	id   string
+	hash string
+	doc  map[string]any
+}
+
 func (e *Request) listQuery(ctx context.Context, field graphql.CollectedField, collection string) (any, error) {
 	iter, err := e.tx.DocumentIterator(ctx, collection)
 	if err != nil {
 		return nil, err
 	}
-	result := make([]any, 0)
+	entries := make([]listEntry, 0)
 	args := field.ArgumentMap(e.params.Variables)
 	for !iter.Done() {
 		id, hash, doc, err := iter.Next(ctx)
 		if err != nil {
 			return nil, err
 		}
-		ctx = context.WithValue(ctx, idContextKey, id)
-		ctx = context.WithValue(ctx, hashContextKey, hash.String())
-		match, err := e.tx.FilterDocument(ctx, collection, id, args["filter"])
-		if err != nil || !match {
+		match, err := e.tx.MatchDocument(ctx, collection, id, doc, args["filter"])
+		if err != nil {
 			return nil, err
 		}
-		res, err := e.queryDocument(ctx, collection, doc, field)
+		if !match {
+			continue
+		}
+		entries = append(entries, listEntry{id: id, hash: hash.String(), doc: doc})
+	}
+	if args["orderBy"] != nil {
+		var sortErr error
+		slices.SortStableFunc(entries, func(a, b listEntry) int {
+			res, err := e.tx.CompareDocuments(collection, a.id, a.doc, b.id, b.doc, args["orderBy"])
+			if err != nil {
+				sortErr = err
+			}
+			return res
+		})
+		if sortErr != nil {
+			return nil, sortErr
+		}
+	}
+	docs := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		docs = append(docs, entry.doc)
+	}
+	err = e.loadRelations(ctx, collection, docs, field)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		ctx := context.WithValue(ctx, idContextKey, entry.id)
+		ctx = context.WithValue(ctx, hashContextKey, entry.hash)
+		res, err := e.queryDocument(ctx, collection, entry.doc, field)
 		if err != nil {
 			return nil, err
 		}
@@ -128,8 +450,62 @@ func (e *Request) listQuery(ctx context.Context, field graphql.CollectedField, c
 	return result, nil
 }
 
+// listAbstractQuery returns the documents of all collections implementing the interface or union type.
+//
+// Documents are ordered by collection name and then by id.
+func (e *Request) listAbstractQuery(ctx context.Context, field graphql.CollectedField, def *ast.Definition) (any, error) {
+	args := field.ArgumentMap(e.params.Variables)
+	result := make([]any, 0)
+	for _, target := range schema_gen.PossibleCollections(e.schema, def) {
+		iter, err := e.tx.DocumentIterator(ctx, target.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]listEntry, 0)
+		docs := make([]map[string]any, 0)
+		for !iter.Done() {
+			id, hash, doc, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			match, err := e.tx.FilterReference(ctx, def.Name, target.Name, id, args["filter"])
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+			entries = append(entries, listEntry{id: id, hash: hash.String(), doc: doc})
+			docs = append(docs, doc)
+		}
+		err = e.loadRelations(ctx, target.Name, docs, field)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ctx := context.WithValue(ctx, idContextKey, entry.id)
+			ctx = context.WithValue(ctx, hashContextKey, entry.hash)
+			res, err := e.queryDocument(ctx, target.Name, entry.doc, field)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, res)
+		}
+	}
+	return result, nil
+}
+
+// satisfies returns the names of the types a document in the collection satisfies for fragment selection.
+func (e *Request) satisfies(collection string) []string {
+	names := []string{collection}
+	for _, def := range e.schema.GetImplements(e.schema.Types[collection]) {
+		names = append(names, def.Name)
+	}
+	return names
+}
+
 func (e *Request) queryDocument(ctx context.Context, collection string, doc map[string]any, field graphql.CollectedField) (any, error) {
-	fields := e.collectFields(field.SelectionSet, collection)
+	fields := e.collectFields(field.SelectionSet, e.satisfies(collection)...)
 	result := make(map[string]any)
 	for _, f := range fields {
 		switch f.Name {
@@ -151,7 +527,15 @@ func (e *Request) queryDocument(ctx context.Context, collection string, doc map[
 			if fd == nil {
 				return nil, fmt.Errorf("invalid document field %s", f.Name)
 			}
-			res, err := e.queryValue(ctx, fd.Type, doc[f.Name], f)
+			value := doc[f.Name]
+			if _, ok := schema_gen.InverseOf(fd); ok {
+				inverse, err := e.tx.InverseRelation(ctx, collection, ctx.Value(idContextKey).(string), f.Name)
+				if err != nil {
+					return nil, err
+				}
+				value = inverse
+			}
+			res, err := e.queryValue(ctx, fd.Type, value, f)
 			if err != nil {
 				return nil, err
 			}
@@ -162,13 +546,22 @@ func (e *Request) queryDocument(ctx context.Context, collection string, doc map[
 }
 
 func (e *Request) queryValue(ctx context.Context, typ *ast.Type, value any, field graphql.CollectedField) (any, error) {
-	if value == nil || len(field.SelectionSet) == 0 {
-		return value, nil
+	if value == nil {
+		return nil, nil
+	}
+	if len(field.SelectionSet) == 0 {
+		return serializeValue(typ, value), nil
 	}
 	if typ.Elem != nil {
 		return e.queryList(ctx, typ, value, field)
 	}
 	def := e.schema.Types[typ.NamedType]
+	if schema_gen.IsEmbedded(def) {
+		return e.queryEmbedded(ctx, def, value, field)
+	}
+	if schema_gen.IsAbstract(def) {
+		return e.queryAbstract(ctx, value, field)
+	}
 	if def.Kind == ast.Object {
 		return e.queryRelation(ctx, typ, value, field)
 	}
@@ -179,14 +572,60 @@ func (e *Request) queryRelation(ctx context.Context, typ *ast.Type, value any, f
 	if value == nil {
 		return nil, nil
 	}
-	doc, err := e.tx.ReadDocument(ctx, typ.NamedType, value.(string))
-	if err != nil {
+	hash, doc, err := e.loader.load(ctx, typ.NamedType, value.(string))
+	if err != nil || doc == nil {
 		return nil, err
 	}
+	ctx = context.WithValue(ctx, idContextKey, value)
+	ctx = context.WithValue(ctx, hashContextKey, hash)
 	return e.queryDocument(ctx, typ.NamedType, doc, field)
 }
 
+// queryAbstract returns the document referenced by the typed reference value.
+func (e *Request) queryAbstract(ctx context.Context, value any, field graphql.CollectedField) (any, error) {
+	collection, id, ok := core.ParseReference(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid reference %v", value)
+	}
+	hash, doc, err := e.loader.load(ctx, collection, id)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, idContextKey, id)
+	ctx = context.WithValue(ctx, hashContextKey, hash)
+	return e.queryDocument(ctx, collection, doc, field)
+}
+
+func (e *Request) queryEmbedded(ctx context.Context, def *ast.Definition, value any, field graphql.CollectedField) (any, error) {
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid embedded value for type %s", def.Name)
+	}
+	fields := e.collectFields(field.SelectionSet, def.Name)
+	result := make(map[string]any)
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			result[f.Alias] = def.Name
+			continue
+		}
+		fd := def.Fields.ForName(f.Name)
+		if fd == nil {
+			return nil, fmt.Errorf("invalid embedded field %s", f.Name)
+		}
+		res, err := e.queryValue(ctx, fd.Type, doc[f.Name], f)
+		if err != nil {
+			return nil, err
+		}
+		result[f.Alias] = res
+	}
+	return result, nil
+}
+
 func (e *Request) queryList(ctx context.Context, typ *ast.Type, value any, field graphql.CollectedField) (any, error) {
+	err := e.loadValues(ctx, typ.Elem, value.([]any), field)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]any, 0)
 	for _, v := range value.([]any) {
 		res, err := e.queryValue(ctx, typ.Elem, v, field)
@@ -2,14 +2,16 @@ package graphql
 
 import (
 	"context"
-	"encoding/hex"
+	"time"
 
 	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/object"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
 )
 
 type contextKey string
@@ -20,56 +22,119 @@ var (
 )
 
 const (
-	createOperationPrefix = "create"
-	updateOperationPrefix = "update"
-	deleteOperationPrefix = "delete"
-	listOperationPrefix   = "list"
-	findOperationPrefix   = "find"
+	createOperationPrefix  = "create"
+	updateOperationPrefix  = "update"
+	deleteOperationPrefix  = "delete"
+	listOperationPrefix    = "list"
+	findOperationPrefix    = "find"
+	historyOperationPrefix = "history"
+	blameOperationPrefix   = "blame"
 )
 
 type Request struct {
 	tx        *core.Transaction
+	loader    *documentLoader
 	schema    *ast.Schema
 	query     *ast.QueryDocument
 	operation *ast.OperationDefinition
 	params    QueryParams
+	// headCommits contains the mutation results that are resolved after the request is committed.
+	headCommits []headCommit
+}
+
+// headCommit is a mutation result containing the selected fields of the new head commit.
+type headCommit struct {
+	result map[string]any
+	field  graphql.CollectedField
 }
 
 func NewRequest(ctx context.Context, repo *core.Repository, params QueryParams) (*Request, error) {
-	query, errs := gqlparser.LoadQuery(repo.Schema(), params.Query)
-	if errs != nil {
-		return nil, errs
-	}
-	var operation *ast.OperationDefinition
-	if params.OperationName != "" {
-		operation = query.Operations.ForName(params.OperationName)
-	} else if len(query.Operations) == 1 {
-		operation = query.Operations[0]
+	// the revision must be known before the query can be validated against its schema
+	doc, err := parser.ParseQuery(&ast.Source{Input: params.Query})
+	if err != nil {
+		return nil, err
 	}
+	operation := selectOperation(doc, params.OperationName)
 	if operation == nil {
 		return nil, gqlerror.Errorf("operation is not defined")
 	}
 	hash := repo.Head()
 	if rev := operation.Directives.ForName("revision"); rev != nil {
-		b, err := hex.DecodeString(rev.Arguments.ForName("hash").Value.Raw)
+		b, err := parseHash(rev.Arguments.ForName("hash").Value.Raw)
+		if err != nil {
+			return nil, err
+		}
+		hash = b
+	}
+	if asOf := operation.Directives.ForName("asOf"); asOf != nil {
+		if operation.Directives.ForName("revision") != nil {
+			return nil, gqlerror.Errorf("revision and asOf cannot be used together")
+		}
+		var value any
+		if arg := asOf.Arguments.ForName("time"); arg != nil {
+			value, _ = arg.Value.Value(params.Variables)
+		}
+		b, err := commitAsOf(ctx, repo, value)
 		if err != nil {
 			return nil, err
 		}
 		hash = b
 	}
-	tx, err := repo.Transaction(ctx, hash)
+	// the query is validated before the transaction is opened so invalid queries do not need to release it
+	schema, err := repo.CommitSchema(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	query, errs := gqlparser.LoadQuery(schema, params.Query)
+	if errs != nil {
+		return nil, errs
+	}
+	open := repo.Transaction
+	if operation.Operation != ast.Mutation {
+		open = repo.ReadTransaction
+	}
+	tx, err := open(ctx, hash)
 	if err != nil {
 		return nil, err
 	}
 	return &Request{
 		tx:        tx,
-		schema:    repo.Schema(),
+		loader:    newDocumentLoader(tx),
+		schema:    tx.Schema(),
 		query:     query,
-		operation: operation,
+		operation: selectOperation(query, params.OperationName),
 		params:    params,
 	}, nil
 }
 
+// commitAsOf returns the hash of the newest commit created at or before the time value.
+func commitAsOf(ctx context.Context, repo *core.Repository, value any) (object.Hash, error) {
+	raw, _ := value.(string)
+	at, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, gqlerror.Errorf("invalid asOf time %q", raw)
+	}
+	hash, err := repo.CommitAsOf(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		return nil, gqlerror.Errorf("no revision exists at or before %s", raw)
+	}
+	return hash, nil
+}
+
+// selectOperation returns the operation with the given name or the only operation if the name is empty.
+func selectOperation(query *ast.QueryDocument, name string) *ast.OperationDefinition {
+	if name != "" {
+		return query.Operations.ForName(name)
+	}
+	if len(query.Operations) == 1 {
+		return query.Operations[0]
+	}
+	return nil
+}
+
 func (e *Request) Execute(ctx context.Context) (any, error) {
 	switch e.operation.Operation {
 	case ast.Mutation:
@@ -81,6 +146,17 @@ func (e *Request) Execute(ctx context.Context) (any, error) {
 	}
 }
 
+// commitInfo returns the commit metadata from the commit directive of the operation.
+func (e *Request) commitInfo() core.CommitInfo {
+	var info core.CommitInfo
+	if dir := e.operation.Directives.ForName("commit"); dir != nil {
+		args := dir.ArgumentMap(e.params.Variables)
+		info.Author, _ = args["author"].(string)
+		info.Message, _ = args["message"].(string)
+	}
+	return info
+}
+
 func (e *Request) collectFields(sel ast.SelectionSet, satisfies ...string) []graphql.CollectedField {
 	reqCtx := &graphql.OperationContext{
 		RawQuery:  e.params.Query,
@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"math/big"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// serializeValue returns the value converted to its GraphQL response representation.
+func serializeValue(typ *ast.Type, value any) any {
+	if value == nil {
+		return nil
+	}
+	if typ.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		out := make([]any, len(list))
+		for i, v := range list {
+			out[i] = serializeValue(typ.Elem, v)
+		}
+		return out
+	}
+	switch v := value.(type) {
+	case time.Time:
+		if typ.NamedType == "Date" {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case *big.Int:
+		return v.String()
+	case *big.Rat:
+		return formatDecimal(v)
+	default:
+		return value
+	}
+}
+
+// formatDecimal returns the decimal formatted with the minimum number of fractional digits.
+func formatDecimal(value *big.Rat) string {
+	// decimals parsed from strings have denominators of the form 2^a * 5^b
+	denom := new(big.Int).Set(value.Denom())
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	var twos, fives int
+	for mod.Mod(denom, two).Sign() == 0 {
+		denom.Div(denom, two)
+		twos++
+	}
+	for mod.Mod(denom, five).Sign() == 0 {
+		denom.Div(denom, five)
+		fives++
+	}
+	if !denom.IsInt64() || denom.Int64() != 1 {
+		return value.FloatString(32) // not a finite decimal
+	}
+	return value.FloatString(max(twos, fives))
+}
@@ -0,0 +1,419 @@
+package schema_gen
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// validateDirectives ensures the directives in the input schema are used correctly.
+func validateDirectives(schema *ast.Schema) error {
+	for _, def := range schema.Types {
+		if !IsEmbedded(def) {
+			continue
+		}
+		err := validateEmbeddedType(schema, def)
+		if err != nil {
+			return err
+		}
+	}
+	for _, def := range schema.Types {
+		if !IsAbstract(def) {
+			continue
+		}
+		err := validateAbstractType(schema, def)
+		if err != nil {
+			return err
+		}
+	}
+	for _, def := range schema.Types {
+		if !IsCollection(def) {
+			continue
+		}
+		err := validateIDDirective(def)
+		if err != nil {
+			return err
+		}
+		err = validateUniqueDirectives(def)
+		if err != nil {
+			return err
+		}
+		for _, field := range def.Fields {
+			err := validateRelationDirective(schema, def, field)
+			if err != nil {
+				return err
+			}
+			err = validateInverseDirective(schema, def, field)
+			if err != nil {
+				return err
+			}
+			err = validateConstraintDirectives(def, field)
+			if err != nil {
+				return err
+			}
+			err = validateGeneratedDirectives(schema, def, field)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateEmbeddedType ensures the embedded type only contains values that can be stored inline.
+func validateEmbeddedType(schema *ast.Schema, def *ast.Definition) error {
+	if def.Directives.ForName("id") != nil || def.Directives.ForName("unique") != nil {
+		return fmt.Errorf("id and unique directives are not valid on embedded type %s", def.Name)
+	}
+	for _, field := range def.Fields {
+		target := schema.Types[field.Type.Name()]
+		if !target.IsLeafType() && !IsEmbedded(target) {
+			return fmt.Errorf("embedded type fields must be scalars, enums, or embedded types: %s.%s", def.Name, field.Name)
+		}
+		for _, dir := range field.Directives {
+			switch dir.Name {
+			case "relation", "inverse", "unique", "createdAt", "updatedAt", "autoIncrementPerBranch":
+				return fmt.Errorf("%s directive is not valid on embedded type fields: %s.%s", dir.Name, def.Name, field.Name)
+			}
+		}
+		err := validateConstraintDirectives(def, field)
+		if err != nil {
+			return err
+		}
+		err = validateGeneratedDirectives(schema, def, field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAbstractType ensures the interface or union type can be stored as a reference to a document.
+func validateAbstractType(schema *ast.Schema, def *ast.Definition) error {
+	for _, t := range schema.GetPossibleTypes(def) {
+		if t.Kind == ast.Object && !IsCollection(t) {
+			return fmt.Errorf("possible type %s of %s must be a collection", t.Name, def.Name)
+		}
+		if def.Fields.ForName(t.Name) != nil {
+			return fmt.Errorf("field name %s is reserved on type %s", t.Name, def.Name)
+		}
+	}
+	return nil
+}
+
+// validateIDDirective ensures the id strategy key fields exist.
+func validateIDDirective(def *ast.Definition) error {
+	dir := def.Directives.ForName("id")
+	if dir == nil {
+		return nil
+	}
+	args := dir.ArgumentMap(nil)
+	fields, _ := args["fields"].([]any)
+	if len(fields) > 0 && args["strategy"] != "HASH" {
+		return fmt.Errorf("id fields are only supported by the HASH strategy: %s", def.Name)
+	}
+	for _, f := range fields {
+		if def.Fields.ForName(f.(string)) == nil {
+			return fmt.Errorf("invalid id field %s on type %s", f, def.Name)
+		}
+	}
+	return nil
+}
+
+// validateUniqueDirectives ensures the unique directives reference stored fields of the type.
+func validateUniqueDirectives(def *ast.Definition) error {
+	for _, dir := range def.Directives.ForNames("unique") {
+		fields, _ := dir.ArgumentMap(nil)["fields"].([]any)
+		if len(fields) == 0 {
+			return fmt.Errorf("unique directive on type %s requires fields", def.Name)
+		}
+		for _, f := range fields {
+			field := def.Fields.ForName(f.(string))
+			if field == nil {
+				return fmt.Errorf("invalid unique field %s on type %s", f, def.Name)
+			}
+			if _, ok := InverseOf(field); ok {
+				return fmt.Errorf("unique field %s on type %s cannot be an inverse field", f, def.Name)
+			}
+		}
+	}
+	for _, field := range def.Fields {
+		for _, dir := range field.Directives.ForNames("unique") {
+			if _, ok := dir.ArgumentMap(nil)["fields"]; ok {
+				return fmt.Errorf("unique directive fields are only valid on types: %s.%s", def.Name, field.Name)
+			}
+			if _, ok := InverseOf(field); ok {
+				return fmt.Errorf("unique directive is not valid on inverse fields: %s.%s", def.Name, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRelationDirective ensures the relation directive is only used on relation fields.
+func validateRelationDirective(schema *ast.Schema, def *ast.Definition, field *ast.FieldDefinition) error {
+	dir := field.Directives.ForName("relation")
+	if dir == nil {
+		return nil
+	}
+	target := schema.Types[field.Type.Name()]
+	if !IsCollection(target) && !IsAbstract(target) {
+		return fmt.Errorf("relation directive is only valid on relation fields: %s.%s", def.Name, field.Name)
+	}
+	action := dir.ArgumentMap(nil)["onDelete"]
+	if action == "SET_NULL" && field.Type.Elem == nil && field.Type.NonNull {
+		return fmt.Errorf("SET_NULL is not valid on non-null relation fields: %s.%s", def.Name, field.Name)
+	}
+	return nil
+}
+
+// validateInverseDirective ensures the inverse directive references a relation field of this type.
+func validateInverseDirective(schema *ast.Schema, def *ast.Definition, field *ast.FieldDefinition) error {
+	of, ok := InverseOf(field)
+	if !ok {
+		return nil
+	}
+	target := schema.Types[field.Type.Name()]
+	if !IsCollection(target) {
+		return fmt.Errorf("inverse directive is only valid on relation fields: %s.%s", def.Name, field.Name)
+	}
+	relation := target.Fields.ForName(of)
+	if relation == nil || relation.Type.Name() != def.Name {
+		return fmt.Errorf("inverse field %s.%s must reference a relation field of type %s", def.Name, field.Name, def.Name)
+	}
+	if _, ok := InverseOf(relation); ok {
+		return fmt.Errorf("inverse field %s.%s cannot reference another inverse field", def.Name, field.Name)
+	}
+	if field.Type.Elem != nil {
+		return nil
+	}
+	// singular inverse fields must reference at most one document and may reference none
+	if field.Type.NonNull {
+		return fmt.Errorf("inverse field %s.%s cannot be non-null", def.Name, field.Name)
+	}
+	if relation.Type.Elem != nil || relation.Directives.ForName("unique") == nil {
+		return fmt.Errorf("inverse field %s.%s must be a list unless %s.%s is unique", def.Name, field.Name, target.Name, of)
+	}
+	return nil
+}
+
+// validateConstraintDirectives ensures the constraint directives are valid for the field type.
+func validateConstraintDirectives(def *ast.Definition, field *ast.FieldDefinition) error {
+	name := field.Type.Name()
+	if dir := field.Directives.ForName("length"); dir != nil {
+		if field.Type.Elem == nil && name != "String" {
+			return fmt.Errorf("length directive is only valid on String and list fields: %s.%s", def.Name, field.Name)
+		}
+		min, max, err := ConstraintBounds(dir)
+		if err != nil {
+			return fmt.Errorf("invalid length directive on %s.%s: %w", def.Name, field.Name, err)
+		}
+		if min != nil && *min < 0 {
+			return fmt.Errorf("invalid length directive on %s.%s: min must not be negative", def.Name, field.Name)
+		}
+		if max != nil && *max < 0 {
+			return fmt.Errorf("invalid length directive on %s.%s: max must not be negative", def.Name, field.Name)
+		}
+	}
+	if dir := field.Directives.ForName("range"); dir != nil {
+		if name != "Int" && name != "Float" {
+			return fmt.Errorf("range directive is only valid on Int and Float fields: %s.%s", def.Name, field.Name)
+		}
+		_, _, err := ConstraintBounds(dir)
+		if err != nil {
+			return fmt.Errorf("invalid range directive on %s.%s: %w", def.Name, field.Name, err)
+		}
+	}
+	if dir := field.Directives.ForName("pattern"); dir != nil {
+		if name != "String" {
+			return fmt.Errorf("pattern directive is only valid on String fields: %s.%s", def.Name, field.Name)
+		}
+		regex, _ := dir.ArgumentMap(nil)["regex"].(string)
+		_, err := regexp.Compile(regex)
+		if err != nil {
+			return fmt.Errorf("invalid pattern directive on %s.%s: %w", def.Name, field.Name, err)
+		}
+	}
+	if dir := field.Directives.ForName("format"); dir != nil {
+		if name != "String" {
+			return fmt.Errorf("format directive is only valid on String fields: %s.%s", def.Name, field.Name)
+		}
+	}
+	return nil
+}
+
+// validateGeneratedDirectives ensures the default and generated value directives are valid for the field type.
+func validateGeneratedDirectives(schema *ast.Schema, def *ast.Definition, field *ast.FieldDefinition) error {
+	count := 0
+	for _, name := range generatedDirectives {
+		if field.Directives.ForName(name) != nil {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	if count > 1 {
+		return fmt.Errorf("only one default or generated value directive is allowed: %s.%s", def.Name, field.Name)
+	}
+	if _, ok := InverseOf(field); ok {
+		return fmt.Errorf("default values are not valid on inverse fields: %s.%s", def.Name, field.Name)
+	}
+	name := field.Type.Name()
+	if field.Directives.ForName("createdAt") != nil || field.Directives.ForName("updatedAt") != nil {
+		if field.Type.Elem != nil || (name != "String" && name != "Int" && name != "DateTime") {
+			return fmt.Errorf("timestamp directives are only valid on DateTime, String and Int fields: %s.%s", def.Name, field.Name)
+		}
+	}
+	if field.Directives.ForName("autoIncrementPerBranch") != nil {
+		if field.Type.Elem != nil || name != "Int" {
+			return fmt.Errorf("autoIncrementPerBranch directive is only valid on Int fields: %s.%s", def.Name, field.Name)
+		}
+	}
+	if dir := field.Directives.ForName("default"); dir != nil {
+		if !schema.Types[name].IsLeafType() {
+			return fmt.Errorf("default directive is not valid on relation fields: %s.%s", def.Name, field.Name)
+		}
+		value, _ := DefaultValue(field)
+		if !validLiteral(schema, field.Type, value) {
+			return fmt.Errorf("default value does not match type %s: %s.%s", field.Type.String(), def.Name, field.Name)
+		}
+	}
+	return nil
+}
+
+// validLiteral returns true if the literal value is valid for the given type.
+func validLiteral(schema *ast.Schema, typ *ast.Type, value any) bool {
+	if value == nil {
+		return !typ.NonNull
+	}
+	if typ.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			return validLiteral(schema, typ.Elem, value) // single values are coerced to lists
+		}
+		for _, v := range list {
+			if !validLiteral(schema, typ.Elem, v) {
+				return false
+			}
+		}
+		return true
+	}
+	def := schema.Types[typ.NamedType]
+	switch typ.NamedType {
+	case "Int":
+		_, ok := value.(int64)
+		return ok
+	case "Float":
+		switch value.(type) {
+		case int64, float64:
+			return true
+		}
+		return false
+	case "String", "ID":
+		_, ok := value.(string)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	}
+	if def.Kind == ast.Enum {
+		str, ok := value.(string)
+		return ok && def.EnumValues.ForName(str) != nil
+	}
+	return true
+}
+
+// generatedDirectives contains the names of directives that set field values.
+var generatedDirectives = []string{"default", "createdAt", "updatedAt", "autoIncrementPerBranch"}
+
+// IsGenerated returns true if the field has a default or generated value.
+func IsGenerated(field *ast.FieldDefinition) bool {
+	for _, name := range generatedDirectives {
+		if field.Directives.ForName(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultValue returns the value of the default directive on the field.
+func DefaultValue(field *ast.FieldDefinition) (any, bool) {
+	dir := field.Directives.ForName("default")
+	if dir == nil {
+		return nil, false
+	}
+	arg := dir.Arguments.ForName("value")
+	if arg == nil {
+		return nil, false
+	}
+	value, err := arg.Value.Value(nil)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// ConstraintBounds returns the min and max arguments of a length or range directive.
+func ConstraintBounds(dir *ast.Directive) (*float64, *float64, error) {
+	args := dir.ArgumentMap(nil)
+	min, err := constraintBound(args["min"])
+	if err != nil {
+		return nil, nil, err
+	}
+	max, err := constraintBound(args["max"])
+	if err != nil {
+		return nil, nil, err
+	}
+	if min != nil && max != nil && *min > *max {
+		return nil, nil, fmt.Errorf("min must not be greater than max")
+	}
+	return min, max, nil
+}
+
+// constraintBound returns the numeric value of a min or max argument.
+func constraintBound(value any) (*float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		f := float64(v)
+		return &f, nil
+	case float64:
+		return &v, nil
+	default:
+		return nil, fmt.Errorf("bounds must be numbers")
+	}
+}
+
+// UniqueConstraints returns the sets of fields that must be unique within the collection.
+//
+// Auto increment fields are implicitly unique so concurrent changes that assign the same value conflict.
+func UniqueConstraints(def *ast.Definition) [][]string {
+	var constraints [][]string
+	for _, field := range def.Fields {
+		if field.Directives.ForName("unique") != nil || field.Directives.ForName("autoIncrementPerBranch") != nil {
+			constraints = append(constraints, []string{field.Name})
+		}
+	}
+	for _, dir := range def.Directives.ForNames("unique") {
+		list, _ := dir.ArgumentMap(nil)["fields"].([]any)
+		fields := make([]string, len(list))
+		for i, f := range list {
+			fields[i] = f.(string)
+		}
+		constraints = append(constraints, fields)
+	}
+	return constraints
+}
+
+// InverseOf returns the name of the relation field the inverse field is computed from.
+func InverseOf(field *ast.FieldDefinition) (string, bool) {
+	dir := field.Directives.ForName("inverse")
+	if dir == nil {
+		return "", false
+	}
+	of, _ := dir.ArgumentMap(nil)["of"].(string)
+	return of, true
+}
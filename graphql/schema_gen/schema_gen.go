@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/vektah/gqlparser/v2"
@@ -15,14 +16,54 @@ var preludeSource string
 
 // Execute creates a GraphQL schema from the given IPLD schema.TypeSystem.
 func Execute(input string) (*ast.Schema, error) {
-	inputSource := ast.Source{Input: input}
-	inputSchema, err := gqlparser.LoadSchema(&inputSource)
+	inputSchema, err := LoadInput(input)
+	if err != nil {
+		return nil, err
+	}
+	err = validateFields(inputSchema)
+	if err != nil {
+		return nil, err
+	}
+	err = validateDirectives(inputSchema)
 	if err != nil {
 		return nil, err
 	}
 	var output strings.Builder
 	for _, def := range inputSchema.Types {
-		if def.BuiltIn || def.Kind != ast.Object {
+		if def.BuiltIn || def.Kind != ast.Enum {
+			continue
+		}
+		_, err = scalarInputs(def.Name, true, &output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range customScalars {
+		_, err = scalarInputs(name, name != "JSON", &output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, def := range inputSchema.Types {
+		if !IsEmbedded(def) {
+			continue
+		}
+		_, err = embeddedInputs(def, inputSchema, &output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, def := range inputSchema.Types {
+		if !IsAbstract(def) {
+			continue
+		}
+		_, err = abstractInputs(def, inputSchema, &output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, def := range inputSchema.Types {
+		if !IsCollection(def) {
 			continue
 		}
 		_, err = documentType(def, &output)
@@ -37,7 +78,11 @@ func Execute(input string) (*ast.Schema, error) {
 		if err != nil {
 			return nil, err
 		}
-		_, err = documentPatchInput(def, &output)
+		_, err = documentPatchInput(def, inputSchema, &output)
+		if err != nil {
+			return nil, err
+		}
+		_, err = documentRelationPatchInput(def, &output)
 		if err != nil {
 			return nil, err
 		}
@@ -49,6 +94,10 @@ func Execute(input string) (*ast.Schema, error) {
 		if err != nil {
 			return nil, err
 		}
+		_, err = documentOrderByInput(def, inputSchema, &output)
+		if err != nil {
+			return nil, err
+		}
 	}
 	_, err = queryType(inputSchema, &output)
 	if err != nil {
@@ -59,26 +108,108 @@ func Execute(input string) (*ast.Schema, error) {
 		return nil, err
 	}
 	preludeSource := ast.Source{Input: preludeSource, BuiltIn: true}
+	inputSource := ast.Source{Input: input}
 	outputSource := ast.Source{Input: output.String()}
 	return gqlparser.LoadSchema(&preludeSource, &inputSource, &outputSource)
 }
 
+// LoadInput parses the given input schema along with the prelude definitions.
+func LoadInput(input string) (*ast.Schema, error) {
+	preludeSource := ast.Source{Input: preludeSource, BuiltIn: true}
+	inputSource := ast.Source{Input: input}
+	return gqlparser.LoadSchema(&preludeSource, &inputSource)
+}
+
+// customScalars contains the names of scalars defined in the prelude that have generated inputs.
+var customScalars = []string{"DateTime", "Date", "JSON", "Bytes", "Decimal", "BigInt"}
+
+// systemFields contains field names that are generated on document types and not stored.
+var systemFields = []string{"id", "hash"}
+
+// IsSystemField returns true if the field with the given name is generated on document types and not stored.
+func IsSystemField(name string) bool {
+	return slices.Contains(systemFields, name)
+}
+
+// reservedEmbeddedFields contains field names that are used by generated embedded types.
+var reservedEmbeddedFields = []string{"set", "unset"}
+
+// validateFields ensures no collection or embedded fields use reserved names.
+func validateFields(schema *ast.Schema) error {
+	for _, def := range schema.Types {
+		if !IsCollection(def) && !IsEmbedded(def) {
+			continue
+		}
+		for _, field := range def.Fields {
+			if slices.Contains(systemFields, field.Name) {
+				return fmt.Errorf("field name %s is reserved on type %s", field.Name, def.Name)
+			}
+			if IsEmbedded(def) && slices.Contains(reservedEmbeddedFields, field.Name) {
+				return fmt.Errorf("field name %s is reserved on embedded type %s", field.Name, def.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// IsCollection returns true if the given definition is stored as a collection of documents.
+func IsCollection(def *ast.Definition) bool {
+	return def != nil && !def.BuiltIn && def.Kind == ast.Object && def.Directives.ForName("embedded") == nil
+}
+
+// IsEmbedded returns true if the given definition is stored inline within documents.
+func IsEmbedded(def *ast.Definition) bool {
+	return def != nil && !def.BuiltIn && def.Kind == ast.Object && def.Directives.ForName("embedded") != nil
+}
+
+// IsAbstract returns true if the given definition is an interface or union of collections.
+func IsAbstract(def *ast.Definition) bool {
+	return def != nil && !def.BuiltIn && (def.Kind == ast.Interface || def.Kind == ast.Union)
+}
+
+// PossibleCollections returns the collections that can be referenced by the abstract type ordered by name.
+func PossibleCollections(schema *ast.Schema, def *ast.Definition) []*ast.Definition {
+	out := make([]*ast.Definition, 0)
+	for _, t := range schema.GetPossibleTypes(def) {
+		if IsCollection(t) && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	slices.SortFunc(out, func(a, b *ast.Definition) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
 // queryType defines the query operations
 func queryType(schema *ast.Schema, w io.Writer) (int, error) {
 	fields := make([]string, 0)
 	for _, def := range schema.Types {
-		if def.BuiltIn || def.Kind != ast.Object {
+		if IsAbstract(def) {
+			fields = append(fields, fmt.Sprintf(`
+	"""
+    List documents of all %[1]s types.
+    """
+    list%[1]s(filter: %[1]sFilterInput): [%[1]s]`, def.Name))
+		}
+		if !IsCollection(def) {
 			continue
 		}
 		fields = append(fields, fmt.Sprintf(`
 	"""
     List %[1]s documents.
     """
-    list%[1]s(filter: %[1]sFilterInput): [%[1]s]
+    list%[1]s(filter: %[1]sFilterInput, orderBy: [%[1]sOrderByInput!]): [%[1]s]
     """
     Find a %[1]s document.
     """
-    find%[1]s(id: ID!): %[1]s`, def.Name))
+    find%[1]s(id: ID!): %[1]s
+    """
+    The versions of a %[1]s document starting from the revision this query is based on.
+    """
+    history%[1]s(id: ID!): [%[1]sVersion!]!
+    """
+    The commits that last set the value of each field of a %[1]s document.
+    """
+    blame%[1]s(id: ID!): [FieldBlame!]!`, def.Name))
 	}
 	return fmt.Fprintf(w, `extend type Query {
 	%s
@@ -87,9 +218,18 @@ func queryType(schema *ast.Schema, w io.Writer) (int, error) {
 
 // mutationType defines the mutation operations
 func mutationType(schema *ast.Schema, w io.Writer) (int, error) {
-	fields := make([]string, 0)
+	fields := []string{`
+    """
+    Undo the changes of the commit with the given hash. Returns the new head commit.
+    """
+    revert(hash: String!): Commit!
+    """
+    Apply the changes of the commit with the given hash. Returns the new head commit.
+    """
+    cherryPick(hash: String!): Commit!
+		`}
 	for _, def := range schema.Types {
-		if def.BuiltIn || def.Kind != ast.Object {
+		if !IsCollection(def) {
 			continue
 		}
 		fields = append(fields, fmt.Sprintf(`
@@ -114,7 +254,7 @@ func mutationType(schema *ast.Schema, w io.Writer) (int, error) {
 
 // documentType extends a document type with generated fields
 func documentType(def *ast.Definition, w io.Writer) (int, error) {
-	return fmt.Fprintf(w, `extend type %s {
+	return fmt.Fprintf(w, `extend type %[1]s {
 	"""
 	The unique identifier of this document.
 	"""
@@ -123,19 +263,31 @@ func documentType(def *ast.Definition, w io.Writer) (int, error) {
 	The hash of this document.
 	"""
 	hash: String!
-}`, def.Name)
+}
+
+"""
+A version of a %[1]s document created by a commit.
+"""
+type %[1]sVersion {
+    """
+    The commit that created this version.
+    """
+    commit: Commit!
+    """
+    The hash of the document. Null if the commit deleted the document.
+    """
+    hash: String
+    """
+    The document at this version. Null if the commit deleted the document.
+    """
+    document: %[1]s
+}
+`, def.Name)
 }
 
 // documentFilterInput is the input type for filtering documents of this type.
 func documentFilterInput(def *ast.Definition, w io.Writer) (int, error) {
-	fields := make([]string, len(def.Fields))
-	for i, field := range def.Fields {
-		if field.Type.Elem != nil {
-			fields[i] = fmt.Sprintf("%s: %sListFilterInput", field.Name, field.Type.Elem.Name())
-		} else {
-			fields[i] = fmt.Sprintf("%s: %sFilterInput", field.Name, field.Type.Name())
-		}
-	}
+	fields := filterFields(def)
 	return fmt.Fprintf(w, `
 """
 Input for filtering %[1]s documents.
@@ -157,6 +309,19 @@ input %[1]sFilterInput {
 }`, def.Name, strings.Join(fields, "\n"))
 }
 
+// filterFields returns the filter input fields of the given type.
+func filterFields(def *ast.Definition) []string {
+	fields := make([]string, len(def.Fields))
+	for i, field := range def.Fields {
+		if field.Type.Elem != nil {
+			fields[i] = fmt.Sprintf("%s: %sListFilterInput", field.Name, field.Type.Elem.Name())
+		} else {
+			fields[i] = fmt.Sprintf("%s: %sFilterInput", field.Name, field.Type.Name())
+		}
+	}
+	return fields
+}
+
 // documentListFilterInput is the input type for filtering lists of documents of this type.
 func documentListFilterInput(def *ast.Definition, w io.Writer) (int, error) {
 	return fmt.Fprintf(w, `
@@ -180,15 +345,8 @@ input %[1]sListFilterInput {
 }
 
 // documentPatchInput is the input type for patching documents of this type
-func documentPatchInput(def *ast.Definition, w io.Writer) (int, error) {
-	fields := make([]string, len(def.Fields))
-	for i, field := range def.Fields {
-		if field.Type.Elem != nil {
-			fields[i] = fmt.Sprintf("%s: %sListPatchInput", field.Name, field.Type.Elem.Name())
-		} else {
-			fields[i] = fmt.Sprintf("%s: %sPatchInput", field.Name, field.Type.Name())
-		}
-	}
+func documentPatchInput(def *ast.Definition, schema *ast.Schema, w io.Writer) (int, error) {
+	fields := patchFields(def, schema)
 	return fmt.Fprintf(w, `
 """
 Input for patching %[1]s documents.
@@ -202,6 +360,53 @@ input %[1]sPatchInput {
 }`, def.Name, strings.Join(fields, "\n"))
 }
 
+// documentRelationPatchInput is the input type for patching relation fields of this type.
+func documentRelationPatchInput(def *ast.Definition, w io.Writer) (int, error) {
+	return fmt.Fprintf(w, `
+"""
+Input for patching %[1]s relation fields.
+
+Only one operation can be used.
+"""
+input %[1]sRelationPatchInput {
+    """
+    Points the relation at an existing %[1]s document.
+    """
+    connect: ID
+    """
+    Removes the relation.
+    """
+    disconnect: Boolean
+    """
+    Points the relation at a new %[1]s document.
+    """
+    create: %[1]sCreateInput
+    """
+    Patches the related %[1]s document.
+    """
+    update: %[1]sPatchInput
+}`, def.Name)
+}
+
+// patchFields returns the patch input fields of the given type.
+func patchFields(def *ast.Definition, schema *ast.Schema) []string {
+	fields := make([]string, 0, len(def.Fields))
+	for _, field := range def.Fields {
+		if _, ok := InverseOf(field); ok {
+			continue // inverse fields are computed
+		}
+		switch {
+		case field.Type.Elem != nil:
+			fields = append(fields, fmt.Sprintf("%s: %sListPatchInput", field.Name, field.Type.Elem.Name()))
+		case IsCollection(schema.Types[field.Type.Name()]):
+			fields = append(fields, fmt.Sprintf("%s: %sRelationPatchInput", field.Name, field.Type.Name()))
+		default:
+			fields = append(fields, fmt.Sprintf("%s: %sPatchInput", field.Name, field.Type.Name()))
+		}
+	}
+	return fields
+}
+
 // documentListPatchInput is the input type for patching document lists of this type.
 func documentListPatchInput(def *ast.Definition, w io.Writer) (int, error) {
 	return fmt.Fprintf(w, `
@@ -218,34 +423,527 @@ input %[1]sListPatchInput {
     """
     append: [%[1]sCreateInput]
     """
+    Prepend values to the field.
+    """
+    prepend: [%[1]sCreateInput]
+    """
+    Insert values into the field at an index.
+    """
+    insertAt: %[1]sListInsertInput
+    """
+    Remove the value at an index from the field.
+    """
+    removeAt: Int
+    """
     Filter values in the field.
     """
     filter: %[1]sListFilterInput
+    """
+    Removes the field value.
+    """
+    unset: Boolean
+    """
+    Appends existing %[1]s documents to the field.
+    """
+    connect: [ID!]
+    """
+    Removes %[1]s documents from the field.
+    """
+    disconnect: [ID!]
+    """
+    Appends new %[1]s documents to the field.
+    """
+    create: [%[1]sCreateInput!]
+    """
+    Patches the related %[1]s documents.
+    """
+    update: %[1]sListUpdateInput
+}
+
+"""
+Input for patching %[1]s documents in list fields.
+"""
+input %[1]sListUpdateInput {
+    """
+    Matches the documents to patch. Defaults to all documents.
+    """
+    filter: %[1]sFilterInput
+    """
+    Patch applied to the matching documents.
+    """
+    patch: %[1]sPatchInput!
+}
+
+"""
+Input for inserting %[1]s documents into list fields.
+"""
+input %[1]sListInsertInput {
+    """
+    Index the values are inserted at.
+    """
+    index: Int!
+    """
+    Values to insert.
+    """
+    values: [%[1]sCreateInput]!
 }`, def.Name)
 }
 
 // documentCreateInput is input for creating documents of this type.
 func documentCreateInput(def *ast.Definition, schema *ast.Schema, w io.Writer) (int, error) {
-	fields := make([]string, len(def.Fields))
-	for i, field := range def.Fields {
+	return fmt.Fprintf(w, `
+"""
+Input for creating %[1]s documents.
+"""
+input %[1]sCreateInput {
+    """
+    The unique identifier of the document.
+
+    When this is the only field set the relationship links to an existing %[1]s document.
+    """
+    id: ID
+	%s
+}`, def.Name, strings.Join(createFields(def, schema), "\n"))
+}
+
+// createFields returns the create input fields of the given type.
+func createFields(def *ast.Definition, schema *ast.Schema) []string {
+	fields := make([]string, 0, len(def.Fields))
+	for _, field := range def.Fields {
+		if _, ok := InverseOf(field); ok {
+			continue // inverse fields are computed
+		}
 		def := schema.Types[field.Type.Name()]
-		if def.IsLeafType() {
-			fields[i] = fmt.Sprintf("%s: %s", field.Name, field.Type.String())
+		if def.IsLeafType() && IsGenerated(field) {
+			fields = append(fields, fmt.Sprintf("%s: %s", field.Name, nullableType(field.Type).String()))
+		} else if def.IsLeafType() {
+			fields = append(fields, fmt.Sprintf("%s: %s", field.Name, field.Type.String()))
 		} else if field.Type.Elem != nil {
-			fields[i] = fmt.Sprintf("%s: [%sCreateInput!]", field.Name, field.Type.Name())
+			fields = append(fields, fmt.Sprintf("%s: [%sCreateInput!]", field.Name, field.Type.Name()))
 		} else {
-			fields[i] = fmt.Sprintf("%s: %sCreateInput", field.Name, field.Type.Name())
+			fields = append(fields, fmt.Sprintf("%s: %sCreateInput", field.Name, field.Type.Name()))
 		}
 	}
-	return fmt.Fprintf(w, `
+	return fields
+}
+
+// embeddedInputs defines the filter, patch, and create input types for the embedded type.
+func embeddedInputs(def *ast.Definition, schema *ast.Schema, w io.Writer) (int, error) {
+	n, err := documentFilterInput(def, w)
+	if err != nil {
+		return n, err
+	}
+	m, err := documentListFilterInput(def, w)
+	n += m
+	if err != nil {
+		return n, err
+	}
+	m, err = fmt.Fprintf(w, `
+"""
+Input for creating %[1]s values.
+"""
+input %[1]sCreateInput {
+	%[2]s
+}
+
+"""
+Input for patching %[1]s values.
+
+Either set, unset, or patches for individual fields can be used.
+"""
+input %[1]sPatchInput {
+    """
+    Sets the value of the field.
+    """
+    set: %[1]sCreateInput
+    """
+    Removes the field value.
+    """
+    unset: Boolean
+	%[3]s
+}
+
+"""
+Input for patching %[1]s list fields.
+"""
+input %[1]sListPatchInput {
+    """
+    Sets the value of the field.
+    """
+    set: [%[1]sCreateInput]
+    """
+    Append values to the field.
+    """
+    append: [%[1]sCreateInput]
+    """
+    Prepend values to the field.
+    """
+    prepend: [%[1]sCreateInput]
+    """
+    Insert values into the field at an index.
+    """
+    insertAt: %[1]sListInsertInput
+    """
+    Remove the value at an index from the field.
+    """
+    removeAt: Int
+    """
+    Filter values in the field.
+    """
+    filter: %[1]sListFilterInput
+    """
+    Removes the field value.
+    """
+    unset: Boolean
+}
+
+"""
+Input for inserting values into %[1]s list fields.
+"""
+input %[1]sListInsertInput {
+    """
+    Index the values are inserted at.
+    """
+    index: Int!
+    """
+    Values to insert.
+    """
+    values: [%[1]sCreateInput]!
+}`, def.Name, strings.Join(createFields(def, schema), "\n"), strings.Join(patchFields(def, schema), "\n"))
+	return n + m, err
+}
+
+// abstractInputs defines the filter, patch, and create input types for the interface or union type.
+//
+// Values of abstract types are references to documents in any of the possible collections.
+func abstractInputs(def *ast.Definition, schema *ast.Schema, w io.Writer) (int, error) {
+	var filters, creates []string
+	if def.Kind == ast.Interface {
+		filters = filterFields(def)
+	}
+	for _, t := range PossibleCollections(schema, def) {
+		filters = append(filters, fmt.Sprintf(`"""
+    Matches %[1]s documents that match the filter.
+    """
+    %[1]s: %[1]sFilterInput`, t.Name))
+		creates = append(creates, fmt.Sprintf(`"""
+    Creates or links to a %[1]s document.
+    """
+    %[1]s: %[1]sCreateInput`, t.Name))
+	}
+	var n int
+	if def.Kind == ast.Interface {
+		m, err := fmt.Fprintf(w, `
+extend interface %s {
+	"""
+	The unique identifier of this document.
+	"""
+	id: ID!
+	"""
+	The hash of this document.
+	"""
+	hash: String!
+}`, def.Name)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	m, err := fmt.Fprintf(w, `
+"""
+Input for filtering %[1]s documents.
+"""
+input %[1]sFilterInput {
+    """
+    Matches if all filters match.
+    """
+    and: [%[1]sFilterInput!]
+    """
+    Matches if one filter matches.
+    """
+    or: [%[1]sFilterInput!]
+    """
+    Matches if the filter does not match.
+    """
+    not: %[1]sFilterInput
+    %[2]s
+}`, def.Name, strings.Join(filters, "\n    "))
+	n += m
+	if err != nil {
+		return n, err
+	}
+	m, err = documentListFilterInput(def, w)
+	n += m
+	if err != nil {
+		return n, err
+	}
+	m, err = fmt.Fprintf(w, `
 """
 Input for creating %[1]s documents.
+
+Exactly one field must be set.
 """
 input %[1]sCreateInput {
+    %[2]s
+}
+
+"""
+Input for referencing an existing %[1]s document.
+"""
+input %[1]sRefInput {
     """
-    This field can be used to create relationships with existing %[1]s documents.
+    The name of the collection containing the document.
     """
-    id: ID
+    collection: String!
+    """
+    The unique identifier of the document.
+    """
+    id: ID!
+}
+
+"""
+Input for patching %[1]s relations.
+"""
+input %[1]sPatchInput {
+    """
+    Points the relation at an existing document.
+    """
+    connect: %[1]sRefInput
+    """
+    Removes the relation.
+    """
+    disconnect: Boolean
+    """
+    Points the relation at a new document.
+    """
+    create: %[1]sCreateInput
+}
+
+"""
+Input for patching %[1]s list relations.
+"""
+input %[1]sListPatchInput {
+    """
+    Sets the value of the field.
+    """
+    set: [%[1]sCreateInput]
+    """
+    Append values to the field.
+    """
+    append: [%[1]sCreateInput]
+    """
+    Prepend values to the field.
+    """
+    prepend: [%[1]sCreateInput]
+    """
+    Insert values into the field at an index.
+    """
+    insertAt: %[1]sListInsertInput
+    """
+    Remove the value at an index from the field.
+    """
+    removeAt: Int
+    """
+    Filter values in the field.
+    """
+    filter: %[1]sListFilterInput
+    """
+    Removes the field value.
+    """
+    unset: Boolean
+    """
+    Appends existing documents to the field.
+    """
+    connect: [%[1]sRefInput!]
+    """
+    Removes documents from the field.
+    """
+    disconnect: [%[1]sRefInput!]
+    """
+    Appends new documents to the field.
+    """
+    create: [%[1]sCreateInput!]
+}
+
+"""
+Input for inserting documents into %[1]s list relations.
+"""
+input %[1]sListInsertInput {
+    """
+    Index the values are inserted at.
+    """
+    index: Int!
+    """
+    Values to insert.
+    """
+    values: [%[1]sCreateInput]!
+}`, def.Name, strings.Join(creates, "\n    "))
+	return n + m, err
+}
+
+// documentOrderByInput is the input type for ordering documents of this type.
+func documentOrderByInput(def *ast.Definition, schema *ast.Schema, w io.Writer) (int, error) {
+	fields := make([]string, 0, len(def.Fields))
+	for _, field := range def.Fields {
+		if field.Type.Elem != nil || !schema.Types[field.Type.Name()].IsLeafType() {
+			continue // only scalar fields can be ordered
+		}
+		fields = append(fields, fmt.Sprintf("%s: OrderDirection", field.Name))
+	}
+	return fmt.Fprintf(w, `
+"""
+Input for ordering %[1]s documents.
+
+Each input should contain a single field. Documents are ordered by id when all fields are equal.
+"""
+input %[1]sOrderByInput {
+    """
+    Orders documents by their unique identifier.
+    """
+    id: OrderDirection
 	%s
 }`, def.Name, strings.Join(fields, "\n"))
 }
+
+// orderedFilters contains the comparison filter fields of ordered scalar types.
+const orderedFilters = `
+    """
+    Matches if the field is greater than the value.
+    """
+    gt: %[1]s
+    """
+    Matches if the field is greater than or equal to the value.
+    """
+    gte: %[1]s
+    """
+    Matches if the field is less than the value.
+    """
+    lt: %[1]s
+    """
+    Matches if the field is less than or equal to the value.
+    """
+    lte: %[1]s`
+
+// scalarInputs defines the filter and patch input types for the enum or scalar type.
+//
+// Comparison filters are only defined for ordered types.
+func scalarInputs(name string, ordered bool, w io.Writer) (int, error) {
+	var comparisons string
+	if ordered {
+		comparisons = fmt.Sprintf(orderedFilters, name)
+	}
+	return fmt.Fprintf(w, `
+"""
+Input for filtering %[1]s fields.
+"""
+input %[1]sFilterInput {
+    """
+    Matches if the field is equal to the value.
+    """
+    eq: %[1]s
+    """
+    Matches if the field is not equal to the value.
+    """
+    neq: %[1]s%[2]s
+    """
+    Matches if the field is included in the list.
+    """
+    in: [%[1]s]
+    """
+    Matches if the field is not included in the list.
+    """
+    nin: [%[1]s]
+}
+
+"""
+Input for filtering %[1]s list fields.
+"""
+input %[1]sListFilterInput {
+    """
+    Matches if all field values match.
+    """
+    all: [%[1]sFilterInput!]
+    """
+    Matches if any field values match.
+    """
+    any: [%[1]sFilterInput!]
+    """
+    Matches if no field values match.
+    """
+    none: [%[1]sFilterInput!]
+}
+
+"""
+Input for patching %[1]s fields.
+"""
+input %[1]sPatchInput {
+    """
+    Sets the value of the field.
+    """
+    set: %[1]s
+}
+
+"""
+Input for patching %[1]s list fields.
+"""
+input %[1]sListPatchInput {
+    """
+    Sets the value of the field.
+    """
+    set: [%[1]s]
+    """
+    Append values to the field.
+    """
+    append: [%[1]s]
+    """
+    Prepend values to the field.
+    """
+    prepend: [%[1]s]
+    """
+    Insert values into the field at an index.
+    """
+    insertAt: %[1]sListInsertInput
+    """
+    Remove the value at an index from the field.
+    """
+    removeAt: Int
+    """
+    Append values to the field that it does not already contain.
+    """
+    addToSet: [%[1]s]
+    """
+    Remove all occurrences of the values from the field.
+    """
+    removeValues: [%[1]s]
+    """
+    Filter values in the field.
+    """
+    filter: %[1]sListFilterInput
+    """
+    Removes the field value.
+    """
+    unset: Boolean
+}
+
+"""
+Input for inserting values into %[1]s list fields.
+"""
+input %[1]sListInsertInput {
+    """
+    Index the values are inserted at.
+    """
+    index: Int!
+    """
+    Values to insert.
+    """
+    values: [%[1]s]!
+}
+`, name, comparisons)
+}
+
+// nullableType returns a copy of the type that is not required.
+func nullableType(typ *ast.Type) *ast.Type {
+	out := *typ
+	out.NonNull = false
+	return &out
+}
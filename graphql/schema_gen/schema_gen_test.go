@@ -10,3 +10,147 @@ func TestExecute(t *testing.T) {
 	_, err := Execute(`type User { name: String }`)
 	require.NoError(t, err)
 }
+
+func TestExecuteInvalidIDFields(t *testing.T) {
+	_, err := Execute(`type User @id(strategy: HASH, fields: ["email"]) { name: String }`)
+	require.Error(t, err)
+}
+
+func TestExecuteReservedField(t *testing.T) {
+	_, err := Execute(`type User { hash: String }`)
+	require.Error(t, err)
+
+	// relation patch operations use their own input so they do not reserve field names
+	_, err = Execute(`type User { connect: String, update: String } type Post { author: User }`)
+	require.NoError(t, err)
+
+	// history and blame are queried by id so they do not reserve field names
+	_, err = Execute(`type User { history: String, blame: String }`)
+	require.NoError(t, err)
+}
+
+func TestExecuteInvalidRelationDirective(t *testing.T) {
+	_, err := Execute(`type User { name: String @relation(onDelete: CASCADE) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { friend: User! @relation(onDelete: SET_NULL) }`)
+	require.Error(t, err)
+}
+
+func TestExecuteInvalidInverseDirective(t *testing.T) {
+	_, err := Execute(`type User { posts: [Post] @inverse(of: "title") } type Post { title: String }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { post: Post @inverse(of: "author") } type Post { author: User }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { post: Post! @inverse(of: "author") } type Post { author: User @unique }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { post: Post @inverse(of: "author") } type Post { author: User @unique }`)
+	require.NoError(t, err)
+}
+
+func TestExecuteInvalidConstraintDirectives(t *testing.T) {
+	_, err := Execute(`type User { age: Int @length(min: 1) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String @length(min: 5, max: 1) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String @range(min: 1) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String @pattern(regex: "[") }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { age: Int @format(kind: EMAIL) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String @length(min: 1, max: 5) @pattern(regex: "^[a-z]+$") tags: [String] @length(max: 3) }`)
+	require.NoError(t, err)
+}
+
+func TestExecuteInvalidUniqueDirective(t *testing.T) {
+	_, err := Execute(`type User @unique { name: String }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User @unique(fields: ["email"]) { name: String }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String @unique(fields: ["name"]) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User @unique(fields: ["first", "last"]) @unique(fields: ["nick"]) { first: String last: String nick: String email: String @unique }`)
+	require.NoError(t, err)
+}
+
+func TestExecuteInvalidGeneratedDirectives(t *testing.T) {
+	_, err := Execute(`type User { name: String @default(value: 5) }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { created: Boolean @createdAt }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { number: String @autoIncrementPerBranch }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { number: Int @default(value: 1) @autoIncrementPerBranch }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type User { name: String! @default(value: "bob") tags: [String] @default(value: ["a"]) created: String! @createdAt updated: Int @updatedAt }`)
+	require.NoError(t, err)
+}
+
+func TestExecuteGeneratedFieldsOptional(t *testing.T) {
+	schema, err := Execute(`type User { name: String! @default(value: "bob") }`)
+	require.NoError(t, err)
+
+	field := schema.Types["UserCreateInput"].Fields.ForName("name")
+	require.NotNil(t, field)
+	require.False(t, field.Type.NonNull)
+}
+
+func TestExecuteEnumInputs(t *testing.T) {
+	schema, err := Execute(`enum Size { SMALL, LARGE } type Box { size: Size sizes: [Size] }`)
+	require.NoError(t, err)
+
+	for _, name := range []string{"SizeFilterInput", "SizeListFilterInput", "SizePatchInput", "SizeListPatchInput", "SizeListInsertInput", "BoxOrderByInput"} {
+		require.NotNil(t, schema.Types[name], name)
+	}
+}
+
+func TestExecuteEmbeddedType(t *testing.T) {
+	schema, err := Execute(`type Address @embedded { city: String } type User { address: Address }`)
+	require.NoError(t, err)
+
+	require.Nil(t, schema.Types["Query"].Fields.ForName("listAddress"))
+	require.NotNil(t, schema.Types["AddressPatchInput"])
+
+	_, err = Execute(`type Address @embedded { owner: User } type User { address: Address }`)
+	require.Error(t, err)
+
+	_, err = Execute(`type Address @embedded { set: String }`)
+	require.Error(t, err)
+}
+
+func TestExecuteAbstractTypes(t *testing.T) {
+	schema, err := Execute(`
+interface Attachment { name: String }
+type Image implements Attachment { name: String width: Int }
+type Video implements Attachment { name: String length: Int }
+union Media = Image | Video
+type Post { attachments: [Attachment] cover: Media @relation(onDelete: CASCADE) }`)
+	require.NoError(t, err)
+
+	require.NotNil(t, schema.Types["Query"].Fields.ForName("listAttachment"))
+	require.NotNil(t, schema.Types["Query"].Fields.ForName("listMedia"))
+	require.NotNil(t, schema.Types["Attachment"].Fields.ForName("id"))
+	require.NotNil(t, schema.Types["AttachmentFilterInput"].Fields.ForName("name"))
+	require.NotNil(t, schema.Types["MediaFilterInput"].Fields.ForName("Image"))
+	require.NotNil(t, schema.Types["MediaCreateInput"].Fields.ForName("Video"))
+	require.NotNil(t, schema.Types["MediaRefInput"])
+
+	_, err = Execute(`type Address @embedded { city: String } type User { name: String } union Owner = Address | User`)
+	require.Error(t, err)
+}
@@ -1,17 +1,31 @@
 package object
 
+import "time"
+
 // Commit contains the state of the collections at point in time.
 type Commit struct {
 	// Parents is the list of parent commits this commit was created from.
 	Parents []Hash
 	// DataRoot is the hash of the data root.
 	DataRoot Hash
+	// Time is when the commit was created.
+	//
+	// Commits created before metadata was recorded have a zero time.
+	Time time.Time
+	// Author identifies who created the commit.
+	Author string
+	// Message describes the changes contained in the commit.
+	Message string
 }
 
 // DataRoot is the root object for all data.
 type DataRoot struct {
 	// Collections is a mapping of names to collection root hashes.
 	Collections map[string]Hash
+	// Schema is the hash of the input schema describing the collections.
+	//
+	// Data roots created before schemas were versioned do not contain a schema.
+	Schema Hash
 }
 
 // Collection is the root object for a collection.
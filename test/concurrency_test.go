@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rodent-software/capy"
+	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentExecute ensures concurrent operations do not race and conflicting mutations are retried.
+func TestConcurrentExecute(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Counter { name: String @unique, value: Int }`
+
+	db, err := capy.Init(ctx, core.NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation { createCounter(data: {id: "a", name: "a", value: 0}) { id } }`})
+	require.Empty(t, result.Errors)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := `mutation { updateCounter(filter: {name: {eq: "a"}}, patch: {value: {increment: 1}}) { id } }`
+			if i%2 == 0 {
+				query = `query { listCounter { value } }`
+			}
+			graphql.Execute(ctx, db, graphql.QueryParams{Query: query})
+		}()
+	}
+	wg.Wait()
+
+	result = graphql.Execute(ctx, db, graphql.QueryParams{Query: `query { findCounter(id: "a") { value } }`})
+	require.Empty(t, result.Errors)
+	value := result.Data.(map[string]any)["findCounter"].(map[string]any)["value"]
+	// increments that conflict after every attempt are not applied
+	assert.Greater(t, value, int64(0))
+	assert.LessOrEqual(t, value, int64(10))
+}
+
+// TestConcurrentAutoIncrement ensures concurrent creates do not assign the same sequence number.
+func TestConcurrentAutoIncrement(t *testing.T) {
+	ctx := context.Background()
+	schema := `type Ticket { number: Int! @autoIncrementPerBranch }`
+
+	db, err := capy.Init(ctx, core.NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var created int
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation { createTicket(data: {}) { id } }`})
+			if len(result.Errors) == 0 {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `query { listTicket { number } }`})
+	require.Empty(t, result.Errors)
+	list := result.Data.(map[string]any)["listTicket"].([]any)
+	require.Len(t, list, created)
+	numbers := make(map[any]struct{})
+	for _, item := range list {
+		numbers[item.(map[string]any)["number"]] = struct{}{}
+	}
+	// creates that conflict after every attempt are not applied
+	assert.Len(t, numbers, created)
+	assert.Greater(t, created, 0)
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rodent-software/capy"
+	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRevertReturnsHead ensures history mutations return the commit they create.
+func TestRevertReturnsHead(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	db, err := capy.Init(ctx, core.NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation { createUser(data: {id: "bob", name: "Bob"}) { id } }`})
+	require.Empty(t, result.Errors)
+	created := db.Head()
+
+	for _, name := range []string{"revert", "cherryPick"} {
+		parent := db.Head()
+		result = graphql.Execute(ctx, db, graphql.QueryParams{
+			Query:     `mutation($hash: String!) @commit(message: "` + name + `") { ` + name + `(hash: $hash) { hash message parents { hash } } }`,
+			Variables: map[string]any{"hash": created.String()},
+		})
+		require.Empty(t, result.Errors)
+		commit := result.Data.(map[string]any)[name].(map[string]any)
+		assert.Equal(t, db.Head().String(), commit["hash"])
+		assert.Equal(t, name, commit["message"])
+		assert.Equal(t, []any{map[string]any{"hash": parent.String()}}, commit["parents"])
+	}
+}
+
+// TestInvalidQueryReleasesCommit ensures queries that fail validation do not keep their revision from GC.
+func TestInvalidQueryReleasesCommit(t *testing.T) {
+	ctx := context.Background()
+	schema := `type User { name: String }`
+
+	db, err := capy.Init(ctx, core.NewMemoryStorage(), schema)
+	require.NoError(t, err)
+	root := db.Head()
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation { createUser(data: {name: "Bob"}) { id } }`})
+	require.Empty(t, result.Errors)
+	first := db.Head()
+	result = graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation { createUser(data: {name: "Alice"}) { id } }`})
+	require.Empty(t, result.Errors)
+
+	result = graphql.Execute(ctx, db, graphql.QueryParams{Query: `query @revision(hash: "` + first.String() + `") { listUser { email } }`})
+	require.NotEmpty(t, result.Errors)
+
+	// the revision is only kept by GC while it is part of the history
+	require.NoError(t, db.Squash(ctx, root, db.Head(), "Add users"))
+	_, err = db.GC(ctx)
+	require.NoError(t, err)
+	_, err = db.Commit(ctx, first)
+	assert.ErrorIs(t, err, core.ErrNotFound)
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rodent-software/capy"
+	"github.com/rodent-software/capy/core"
+	"github.com/rodent-software/capy/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage is a storage backend that counts reads.
+type countingStorage struct {
+	core.Storage
+	gets atomic.Int64
+}
+
+func (s *countingStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	s.gets.Add(1)
+	return s.Storage.Get(ctx, key)
+}
+
+// TestRelationLoading ensures relation documents are read once per request.
+func TestRelationLoading(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User { name: String }
+	type Post { title: String, author: User }`
+	storage := &countingStorage{Storage: core.NewMemoryStorage()}
+
+	db, err := capy.Init(ctx, storage, schema)
+	require.NoError(t, err)
+	db.SetCacheSize(0)
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation {
+		a: createUser(data: {id: "a", name: "Alice"}) { id }
+		b: createUser(data: {id: "b", name: "Bob"}) { id }
+	}`})
+	require.Empty(t, result.Errors)
+	const posts = 50
+	for i := 0; i < posts; i++ {
+		result = graphql.Execute(ctx, db, graphql.QueryParams{
+			Query:     `mutation($data: PostCreateInput!) { createPost(data: $data) { id } }`,
+			Variables: map[string]any{"data": map[string]any{"title": fmt.Sprintf("post %d", i), "author": map[string]any{"id": []string{"a", "b"}[i%2]}}},
+		})
+		require.Empty(t, result.Errors)
+	}
+
+	storage.gets.Store(0)
+	result = graphql.Execute(ctx, db, graphql.QueryParams{Query: `query { listPost { title author { name } } }`})
+	require.Empty(t, result.Errors)
+	list := result.Data.(map[string]any)["listPost"].([]any)
+	require.Len(t, list, posts)
+	for _, item := range list {
+		author := item.(map[string]any)["author"].(map[string]any)
+		assert.Contains(t, []any{"Alice", "Bob"}, author["name"])
+	}
+	// each post is read once and the users collection and documents are read once
+	assert.LessOrEqual(t, storage.gets.Load(), int64(posts+10))
+}
+
+// TestRelationHash ensures documents loaded by relations report their own hash.
+func TestRelationHash(t *testing.T) {
+	ctx := context.Background()
+	schema := `
+	type User { name: String }
+	union Owner = User
+	type Post { title: String, author: User, owner: Owner }`
+
+	db, err := capy.Init(ctx, core.NewMemoryStorage(), schema)
+	require.NoError(t, err)
+
+	result := graphql.Execute(ctx, db, graphql.QueryParams{Query: `mutation {
+		createUser(data: {id: "a", name: "Alice"}) { id }
+		createPost(data: {title: "post", author: {id: "a"}, owner: {User: {id: "b", name: "Bob"}}}) { id }
+	}`})
+	require.Empty(t, result.Errors)
+
+	result = graphql.Execute(ctx, db, graphql.QueryParams{Query: `query {
+		listUser { id hash }
+		findUser(id: "a") { hash }
+		listPost { hash author { hash } owner { ... on User { hash } } }
+	}`})
+	require.Empty(t, result.Errors)
+	data := result.Data.(map[string]any)
+	hashes := make(map[string]any)
+	for _, item := range data["listUser"].([]any) {
+		user := item.(map[string]any)
+		hashes[user["id"].(string)] = user["hash"]
+	}
+	require.Len(t, hashes, 2)
+	assert.NotEqual(t, hashes["a"], hashes["b"])
+	assert.Equal(t, hashes["a"], data["findUser"].(map[string]any)["hash"])
+
+	post := data["listPost"].([]any)[0].(map[string]any)
+	assert.Equal(t, hashes["a"], post["author"].(map[string]any)["hash"])
+	assert.Equal(t, hashes["b"], post["owner"].(map[string]any)["hash"])
+	assert.NotEqual(t, post["hash"], hashes["a"])
+}